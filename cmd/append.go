@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dawsonalex/todo"
+)
+
+// runAppend handles "todo append <n> <text...>", adding text to the end of
+// an item's message.
+func runAppend(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	return runAmend("append", func(message, text string) string {
+		return message + " " + text
+	}, args, stdout, stderr)
+}
+
+// runPrepend handles "todo prepend <n> <text...>", adding text to the start
+// of an item's message, after any priority and dates.
+func runPrepend(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	return runAmend("prepend", func(message, text string) string {
+		return text + " " + message
+	}, args, stdout, stderr)
+}
+
+// runAmend implements append and prepend. join combines the item's current
+// message with the new text; projects, contexts and special keys are then
+// re-derived from the result.
+func runAmend(name string, join func(message, text string) string, args []string, stdout, stderr io.Writer) int {
+	fs, filePath := newFlagSet(name, name+" [flags] <n> <text...>", stderr)
+	if err := parseFlags(fs, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+
+	posArgs := fs.Args()
+	if len(posArgs) < 2 {
+		fs.Usage()
+		return 1
+	}
+
+	path, list, unlock, ok := loadList(*filePath, stderr)
+	if !ok {
+		return 1
+	}
+	defer unlock()
+
+	ids, err := parseIds(list, posArgs[:1])
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: %v\n", err)
+		return 1
+	}
+	id := ids[0]
+
+	item, _ := list.Get(id)
+	item.SetMessage(join(item.Message, strings.Join(posArgs[1:], " ")))
+	item.ResolveDates(now())
+	if err := list.Set(id, item); err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: %v\n", err)
+		return 1
+	}
+
+	if !saveList(path, list, stderr) {
+		return 1
+	}
+
+	printItems([]todo.Item{item}, stdout, true, false)
+	return 0
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun_AppendPrepend(t *testing.T) {
+	pinNow(t, time.Date(2026, 5, 23, 9, 0, 0, 0, time.Local))
+	tests := []struct {
+		name     string
+		args     []string
+		wantLine string
+		wantProj []string
+		wantCtx  []string
+	}{
+		{
+			name:     "append",
+			args:     []string{"append", "1", "+release", "@desk"},
+			wantLine: "(A) 2024-01-01 write notes +docs +release @desk",
+			wantProj: []string{"docs", "release"},
+			wantCtx:  []string{"desk"},
+		},
+		{
+			name:     "prepend keeps priority and date first",
+			args:     []string{"prepend", "1", "@desk", "urgently"},
+			wantLine: "(A) 2024-01-01 @desk urgently write notes +docs",
+			wantProj: []string{"docs"},
+			wantCtx:  []string{"desk"},
+		},
+		{
+			name:     "append writes out relative dates",
+			args:     []string{"append", "1", "due:tomorrow"},
+			wantLine: "(A) 2024-01-01 write notes +docs due:2026-05-24",
+			wantProj: []string{"docs"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeRawFile(t, "(A) 2024-01-01 write notes +docs\n")
+			var stdout, stderr bytes.Buffer
+
+			args := append([]string{tc.args[0], "-f", path}, tc.args[1:]...)
+			if code := run(args, nil, &stdout, &stderr); code != 0 {
+				t.Fatalf("run exited %d: %s", code, stderr.String())
+			}
+
+			items := readItemsFromFile(t, path)
+			if len(items) != 1 {
+				t.Fatalf("want 1 item, got %d", len(items))
+			}
+			text, _ := items[0].MarshalText()
+			if string(text) != tc.wantLine {
+				t.Errorf("line = %q, want %q", text, tc.wantLine)
+			}
+			if !sliceEqual(items[0].Projects, tc.wantProj) || !sliceEqual(items[0].Contexts, tc.wantCtx) {
+				t.Errorf("projects %v contexts %v, want %v %v", items[0].Projects, items[0].Contexts, tc.wantProj, tc.wantCtx)
+			}
+			if want := "1 " + tc.wantLine; strings.TrimSpace(stdout.String()) != want {
+				t.Errorf("stdout = %q, want %q", stdout.String(), want)
+			}
+		})
+	}
+}
+
+func TestRun_AppendMissingText(t *testing.T) {
+	path := writeRawFile(t, "first\n")
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"append", "-f", path, "1"}, nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("run exited %d, want 1", code)
+	}
+	if items := readItemsFromFile(t, path); items[0].Message != "first" {
+		t.Errorf("item changed to %q", items[0].Message)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dawsonalex/todo"
+)
+
+// runArchive handles "todo archive", moving every completed item from the
+// todo file to the end of done.txt and then closing the gaps they leave.
+//
+// done.txt is appended to and synced before the todo file is rewritten, so a
+// crash in between can leave an item in both files but never in neither.
+// The lines being appended are recorded first and the record removed once
+// the todo file is written. If a record is left behind and done.txt still
+// ends with its lines, archiving again counts the items they match as
+// already archived, which completes an interrupted run without duplicating
+// them. Any other completed item is appended, even if done.txt already has
+// an identical line.
+func runArchive(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	fs, filePath := newFlagSet("archive", "archive [flags]", stderr)
+	doneFlag := fs.String("d", "", "path to done.txt file (overrides DONE_FILE env var)")
+	if err := parseFlags(fs, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+
+	path, list, unlock, ok := loadList(*filePath, stderr)
+	if !ok {
+		return 1
+	}
+	defer unlock()
+	donePath := resolveDonePath(path, *doneFlag)
+	unlockDone, ok := lockFile(donePath, stderr)
+	if !ok {
+		return 1
+	}
+	defer unlockDone()
+
+	doneList, err := todo.ReadFile(donePath)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: reading %s: %v\n", donePath, err)
+		return 1
+	}
+	pendingPath, err := pendingArchivePath(path)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: finding archive record: %v\n", err)
+		return 1
+	}
+	written, err := interruptedArchive(pendingPath, doneList)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: reading archive record: %v\n", err)
+		return 1
+	}
+
+	// Match the interrupted run's lines in order, so an identical line
+	// archived by an earlier run is never mistaken for one of them.
+	var done, toAppend []todo.Item
+	matched := 0
+	for _, item := range list.GetAll() {
+		if !item.Done {
+			continue
+		}
+		done = append(done, item)
+		text, _ := item.MarshalText()
+		if matched < len(written) && string(text) == written[matched] {
+			matched++
+			continue
+		}
+		toAppend = append(toAppend, item)
+	}
+	if len(done) == 0 {
+		return 0
+	}
+
+	pending := written
+	for _, item := range toAppend {
+		text, _ := item.MarshalText()
+		pending = append(pending, string(text))
+	}
+	if err := writePendingArchive(pendingPath, pending); err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: writing archive record: %v\n", err)
+		return 1
+	}
+	doneBefore, doneErr := os.ReadFile(donePath) // #nosec G304 -- the user's done file.
+	if doneErr != nil && !os.IsNotExist(doneErr) {
+		_, _ = fmt.Fprintf(stderr, "todo: reading %s: %v\n", donePath, doneErr)
+		return 1
+	}
+	if err := todo.AppendFile(donePath, toAppend); err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: writing %s: %v\n", donePath, err)
+		return 1
+	}
+
+	for _, item := range done {
+		if err := list.Remove(item.ID); err != nil {
+			_, _ = fmt.Fprintf(stderr, "todo: %v\n", err)
+			return 1
+		}
+	}
+	list.Compact()
+	// Undo may be run from another directory, so keep the absolute path.
+	absDone, err := filepath.Abs(donePath)
+	if err != nil {
+		absDone = donePath
+	}
+	if !saveListWithDone(path, list, &doneBackup{path: absDone, exists: doneErr == nil, content: doneBefore}, stderr) {
+		return 1
+	}
+	if err := os.Remove(pendingPath); err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: warning: removing archive record: %v\n", err)
+	}
+
+	printItems(done, stdout, false, false)
+	_, _ = fmt.Fprintf(stdout, "archived %d %s to %s\n", len(done), plural(len(done), "item", "items"), donePath)
+	return 0
+}
+
+// resolveDonePath returns the done.txt path to use: -d flag > DONE_FILE env >
+// done.txt next to the todo file at todoPath.
+func resolveDonePath(todoPath, flagVal string) string {
+	if flagVal != "" {
+		return flagVal
+	}
+	if env, ok := os.LookupEnv("DONE_FILE"); ok && env != "" {
+		return env
+	}
+	return filepath.Join(filepath.Dir(todoPath), "done.txt")
+}
+
+// pendingArchivePath returns the path of the record of the lines an archive
+// of the todo file at path is appending to done.txt. It is kept with the
+// todo file's backups.
+func pendingArchivePath(path string) (string, error) {
+	dir, err := backupDir(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "archive.pending"), nil
+}
+
+// interruptedArchive returns the lines recorded at pendingPath by an archive
+// that did not finish, if doneList still ends with them, and otherwise nil.
+func interruptedArchive(pendingPath string, doneList *todo.List) ([]string, error) {
+	data, err := os.ReadFile(pendingPath) // #nosec G304 -- under the backup directory.
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+
+	items := doneList.GetAll()
+	if len(lines) > len(items) {
+		return nil, nil
+	}
+	for i, item := range items[len(items)-len(lines):] {
+		text, _ := item.MarshalText()
+		if string(text) != lines[i] {
+			return nil, nil
+		}
+	}
+	return lines, nil
+}
+
+// writePendingArchive records lines at pendingPath before they are appended
+// to done.txt.
+func writePendingArchive(pendingPath string, lines []string) error {
+	if err := os.MkdirAll(filepath.Dir(pendingPath), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(pendingPath, []byte(strings.Join(lines, "\n")+"\n"), 0o600)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_Archive(t *testing.T) {
+	path := writeRawFile(t, "x 2024-01-02 2024-01-01 done one\nopen one\n\nx 2024-01-03 2024-01-01 done two\nopen two\n")
+	donePath := filepath.Join(filepath.Dir(path), "done.txt")
+	if err := os.WriteFile(donePath, []byte("x 2023-12-31 2023-12-30 older\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"archive", "-f", path}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "open one\nopen two\n"; string(data) != want {
+		t.Errorf("todo file = %q, want %q", data, want)
+	}
+
+	data, err = os.ReadFile(donePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "x 2023-12-31 2023-12-30 older\nx 2024-01-02 2024-01-01 done one\nx 2024-01-03 2024-01-01 done two\n"
+	if string(data) != want {
+		t.Errorf("done file = %q, want %q", data, want)
+	}
+}
+
+// TestRun_ArchiveResumes simulates a crash after done.txt was written but
+// before the todo file was: archiving again must not duplicate the item.
+func TestRun_ArchiveResumes(t *testing.T) {
+	const doneLine = "x 2024-01-02 2024-01-01 done one\n"
+	path := writeRawFile(t, doneLine+"open one\n")
+	donePath := filepath.Join(t.TempDir(), "archive.txt")
+	if err := os.WriteFile(donePath, []byte(doneLine), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pendingPath, err := pendingArchivePath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writePendingArchive(pendingPath, []string{strings.TrimSuffix(doneLine, "\n")}); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DONE_FILE", donePath)
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"archive", "-f", path}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+
+	data, err := os.ReadFile(donePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != doneLine {
+		t.Errorf("done file = %q, want %q", data, doneLine)
+	}
+	if items := readItemsFromFile(t, path); len(items) != 1 || items[0].Message != "open one" {
+		t.Errorf("todo items = %+v, want only open one", items)
+	}
+	if _, err := os.Stat(pendingPath); !os.IsNotExist(err) {
+		t.Errorf("archive record left behind: %v", err)
+	}
+}
+
+// TestRun_ArchiveRepeatedLine archives an item with the same line as one
+// archived before, which must be appended rather than taken as resumed.
+func TestRun_ArchiveRepeatedLine(t *testing.T) {
+	const doneLine = "x 2026-10-18 2026-10-01 water plants\n"
+	path := writeRawFile(t, doneLine)
+	donePath := filepath.Join(filepath.Dir(path), "done.txt")
+
+	for range 2 {
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"archive", "-f", path}, nil, &stdout, &stderr); code != 0 {
+			t.Fatalf("run exited %d: %s", code, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), "archived 1 item") {
+			t.Errorf("stdout = %q", stdout.String())
+		}
+		if err := os.WriteFile(path, []byte(doneLine), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(donePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := doneLine + doneLine; string(data) != want {
+		t.Errorf("done file = %q, want %q", data, want)
+	}
+}
+
+// TestRun_NoLockFilesBeside checks that the lock files for the todo file
+// and done.txt are kept out of their directory.
+func TestRun_NoLockFilesBeside(t *testing.T) {
+	path := writeRawFile(t, "x 2024-01-02 2024-01-01 done one\nopen one\n")
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"archive", "-f", path}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if want := []string{"done.txt", "todo.txt"}; !sliceEqual(names, want) {
+		t.Errorf("directory holds %q, want %q", names, want)
+	}
+}
+
+func TestResolveDonePath(t *testing.T) {
+	todoPath := filepath.Join("some", "dir", "todo.txt")
+
+	t.Setenv("DONE_FILE", "")
+	if got, want := resolveDonePath(todoPath, ""), filepath.Join("some", "dir", "done.txt"); got != want {
+		t.Errorf("default = %q, want %q", got, want)
+	}
+
+	t.Setenv("DONE_FILE", "env-done.txt")
+	if got := resolveDonePath(todoPath, ""); got != "env-done.txt" {
+		t.Errorf("env = %q, want env-done.txt", got)
+	}
+	if got := resolveDonePath(todoPath, "flag-done.txt"); got != "flag-done.txt" {
+		t.Errorf("flag = %q, want flag-done.txt", got)
+	}
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dawsonalex/todo"
+)
+
+// defaultBackups is how many previous versions of a todo file are kept when
+// TODO_BACKUPS is not set.
+const defaultBackups = 10
+
+// backupCount returns how many previous versions to keep: TODO_BACKUPS env,
+// or defaultBackups. Zero disables backups.
+func backupCount() (int, error) {
+	env, ok := os.LookupEnv("TODO_BACKUPS")
+	if !ok || env == "" {
+		return defaultBackups, nil
+	}
+	n, err := strconv.Atoi(env)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid TODO_BACKUPS %q: want a whole number", env)
+	}
+	return n, nil
+}
+
+// backupDir returns the directory holding backups of the todo file at path,
+// and its lock file: a directory per todo file under TODO_BACKUP_DIR, or else
+// under $XDG_STATE_HOME/todo/backups (~/.local/state/todo/backups by
+// default).
+func backupDir(path string) (string, error) {
+	root := os.Getenv("TODO_BACKUP_DIR")
+	if root == "" {
+		state := os.Getenv("XDG_STATE_HOME")
+		if state == "" {
+			u, err := user.Current()
+			if err != nil {
+				return "", fmt.Errorf("looking up home directory: %w", err)
+			}
+			state = filepath.Join(u.HomeDir, ".local", "state")
+		}
+		root = filepath.Join(state, "todo", "backups")
+	}
+
+	// Name the directory after the real file so every path to it, through
+	// symlinks or relative, shares one set of backups.
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if real, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = real
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(root, filepath.Base(abs)+"-"+hex.EncodeToString(sum[:6])), nil
+}
+
+// snapshot is a stored previous version of a todo file.
+type snapshot struct {
+	path string
+	time time.Time
+	// done is the path of the record of the done file's previous version,
+	// stored with the snapshot by archive, or "" if there is none.
+	done string
+}
+
+// doneBackup is the previous version of the done file, stored with a
+// snapshot of the todo file when a change moved items between them. A
+// "<snapshot>.done.path" file holds the done file's path, and if the done
+// file existed, "<snapshot>.done" holds its content.
+type doneBackup struct {
+	path    string
+	exists  bool
+	content []byte
+}
+
+// undoneDir returns the directory under a todo file's backup directory that
+// holds the versions replaced by "todo undo", for "todo undo -redo".
+func undoneDir(dir string) string {
+	return filepath.Join(dir, "undone")
+}
+
+// listSnapshots returns the backups in dir, oldest first.
+func listSnapshots(dir string) ([]snapshot, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []snapshot
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".txt")
+		if !ok {
+			continue
+		}
+		nanos, err := strconv.ParseInt(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		s := snapshot{path: filepath.Join(dir, entry.Name()), time: time.Unix(0, nanos)}
+		if _, err := os.Stat(filepath.Join(dir, name+".done.path")); err == nil {
+			s.done = filepath.Join(dir, name+".done.path")
+		}
+		snapshots = append(snapshots, s)
+	}
+	slices.SortFunc(snapshots, func(a, b snapshot) int { return a.time.Compare(b.time) })
+	return snapshots, nil
+}
+
+// saveBackup stores content, and done if it is not nil, as the newest backup
+// of the todo file at path and deletes all but the newest keep backups. A
+// new backup means a new change, so the versions kept for "todo undo -redo"
+// are deleted.
+func saveBackup(path string, content []byte, done *doneBackup, keep int) error {
+	if keep == 0 {
+		return nil
+	}
+	dir, err := backupDir(path)
+	if err != nil {
+		return err
+	}
+	if err := storeSnapshot(dir, content, done, keep); err != nil {
+		return err
+	}
+	return os.RemoveAll(undoneDir(dir))
+}
+
+// storeSnapshot stores content, and done if it is not nil, as the newest
+// snapshot in dir and deletes all but the newest keep snapshots.
+func storeSnapshot(dir string, content []byte, done *doneBackup, keep int) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	// Zero-padded so names sort in time order.
+	name := fmt.Sprintf("%020d", time.Now().UnixNano())
+	if done != nil {
+		if done.exists {
+			if err := os.WriteFile(filepath.Join(dir, name+".done"), done.content, 0o600); err != nil {
+				return err
+			}
+		}
+		if err := os.WriteFile(filepath.Join(dir, name+".done.path"), []byte(done.path+"\n"), 0o600); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".txt"), content, 0o600); err != nil {
+		return err
+	}
+
+	snapshots, err := listSnapshots(dir)
+	if err != nil {
+		return err
+	}
+	for len(snapshots) > keep {
+		if err := removeSnapshot(snapshots[0]); err != nil {
+			return err
+		}
+		snapshots = snapshots[1:]
+	}
+	return nil
+}
+
+// removeSnapshot deletes s and any done file version stored with it.
+func removeSnapshot(s snapshot) error {
+	if s.done != "" {
+		if err := os.Remove(doneContentPath(s.done)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.Remove(s.done); err != nil {
+			return err
+		}
+	}
+	return os.Remove(s.path)
+}
+
+// doneContentPath returns the path of the done file content stored with
+// the record at donePath.
+func doneContentPath(donePath string) string {
+	return strings.TrimSuffix(donePath, ".path")
+}
+
+// readDoneBackup reads the path of the done file from the record at path,
+// and whether the done file existed. Its content is left in the store.
+func readDoneBackup(path string) (*doneBackup, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- under the backup directory.
+	if err != nil {
+		return nil, err
+	}
+	donePath := strings.TrimSpace(string(data))
+	if donePath == "" {
+		return nil, fmt.Errorf("%s: missing done file path", path)
+	}
+	_, err = os.Stat(doneContentPath(path))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return &doneBackup{path: donePath, exists: err == nil}, nil
+}
+
+// runUndo handles "todo undo", restoring the todo file to its most recent
+// backup, "todo undo -redo", reversing the last undo, and "todo undo -list",
+// showing what each backup's change did. A change made by archive is undone
+// in done.txt too. Each undo keeps the version it replaces for -redo, and
+// each redo keeps one as a backup again, until a new change is made.
+func runUndo(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	fs, filePath := newFlagSet("undo", "undo [flags]", stderr)
+	list := fs.Bool("list", false, "list the stored backups and the change made after each, newest first")
+	redo := fs.Bool("redo", false, "reverse the last undo")
+	if err := parseFlags(fs, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+
+	path, current, unlock, ok := loadList(*filePath, stderr)
+	if !ok {
+		return 1
+	}
+	defer unlock()
+
+	dir, err := backupDir(path)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: finding backups: %v\n", err)
+		return 1
+	}
+	from, to := dir, undoneDir(dir)
+	if *redo {
+		from, to = to, from
+	}
+	snapshots, err := listSnapshots(from)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: reading backups: %v\n", err)
+		return 1
+	}
+	if len(snapshots) == 0 {
+		if *redo {
+			_, _ = fmt.Fprintln(stderr, "todo: nothing to redo")
+		} else {
+			_, _ = fmt.Fprintf(stderr, "todo: no backups of %s to restore\n", path)
+		}
+		return 1
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- the user's todo file.
+	if err != nil && !os.IsNotExist(err) {
+		_, _ = fmt.Fprintf(stderr, "todo: reading %s: %v\n", path, err)
+		return 1
+	}
+
+	if *list {
+		// Each backup's change is the difference between it and the
+		// version after it: the next backup, or for the newest, the file
+		// as it is now.
+		versions := make([]string, 0, len(snapshots)+1)
+		for _, s := range snapshots {
+			data, err := os.ReadFile(s.path)
+			if err != nil {
+				_, _ = fmt.Fprintf(stderr, "todo: reading backup: %v\n", err)
+				return 1
+			}
+			versions = append(versions, string(data))
+		}
+		versions = append(versions, string(data))
+		for i := len(snapshots) - 1; i >= 0; i-- {
+			_, _ = fmt.Fprintf(stdout, "%d  %s\n", len(snapshots)-i, snapshots[i].time.Format("2006-01-02 15:04:05"))
+			printChange(stdout, versions[i], versions[i+1])
+			if snapshots[i].done != "" {
+				_, _ = fmt.Fprintln(stdout, "   (and the items moved to the done file)")
+			}
+		}
+		return 0
+	}
+
+	latest := snapshots[len(snapshots)-1]
+	restored, err := todo.ReadFile(latest.path)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: reading backup: %v\n", err)
+		return 1
+	}
+	saved, err := os.ReadFile(latest.path)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: reading backup: %v\n", err)
+		return 1
+	}
+
+	// Keep the versions being replaced, so this can be reversed.
+	var done, doneNow *doneBackup
+	if latest.done != "" {
+		if done, err = readDoneBackup(latest.done); err != nil {
+			_, _ = fmt.Fprintf(stderr, "todo: reading backup: %v\n", err)
+			return 1
+		}
+		unlockDone, ok := lockFile(done.path, stderr)
+		if !ok {
+			return 1
+		}
+		defer unlockDone()
+		content, err := os.ReadFile(done.path)
+		if err != nil && !os.IsNotExist(err) {
+			_, _ = fmt.Fprintf(stderr, "todo: reading %s: %v\n", done.path, err)
+			return 1
+		}
+		doneNow = &doneBackup{path: done.path, exists: err == nil, content: content}
+	}
+
+	// Make sure nothing changed the file since loadList read it.
+	if err := todo.CheckFile(path, current); err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: writing %s: %v\n", path, err)
+		return 1
+	}
+	keep, err := backupCount()
+	if err == nil && keep > 0 {
+		err = storeSnapshot(to, data, doneNow, keep)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: keeping the current version: %v\n", err)
+		return 1
+	}
+
+	if err := todo.WriteFile(path, restored); err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: writing %s: %v\n", path, err)
+		return 1
+	}
+	if done != nil {
+		if err := restoreDone(latest.done, done); err != nil {
+			_, _ = fmt.Fprintf(stderr, "todo: writing %s: %v\n", done.path, err)
+			return 1
+		}
+	}
+	if err := removeSnapshot(latest); err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: removing restored backup: %v\n", err)
+		return 1
+	}
+
+	if *redo {
+		_, _ = fmt.Fprintln(stdout, "redid the change:")
+		printChange(stdout, string(data), string(saved))
+	} else {
+		_, _ = fmt.Fprintf(stdout, "undid the change made after %s:\n", latest.time.Format("2006-01-02 15:04:05"))
+		printChange(stdout, string(saved), string(data))
+	}
+	if done != nil {
+		_, _ = fmt.Fprintf(stdout, "and restored %s\n", done.path)
+	}
+	return 0
+}
+
+// restoreDone puts back the done file version stored with the record at
+// record: its content, written as the todo file is, or if the done file did
+// not exist, no file.
+func restoreDone(record string, done *doneBackup) error {
+	if !done.exists {
+		if err := os.Remove(done.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	list, err := todo.ReadFile(doneContentPath(record))
+	if err != nil {
+		return err
+	}
+	return todo.WriteFile(done.path, list)
+}
+
+// printChange writes the lines removed ("-") and added ("+") between the
+// before and after versions of a file, ignoring blank lines and order.
+func printChange(w io.Writer, before, after string) {
+	count := make(map[string]int)
+	for _, line := range strings.Split(after, "\n") {
+		count[strings.TrimSpace(line)]++
+	}
+	for _, line := range strings.Split(before, "\n") {
+		line = strings.TrimSpace(line)
+		if count[line] > 0 {
+			count[line]--
+			continue
+		}
+		if line != "" {
+			_, _ = fmt.Fprintf(w, "   - %s\n", line)
+		}
+	}
+	for _, line := range strings.Split(after, "\n") {
+		line = strings.TrimSpace(line)
+		if count[line] > 0 && line != "" {
+			count[line]--
+			_, _ = fmt.Fprintf(w, "   + %s\n", line)
+		}
+	}
+}
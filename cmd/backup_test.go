@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_Undo(t *testing.T) {
+	t.Setenv("TODO_BACKUP_DIR", t.TempDir())
+	const content = "first\nsecond\n"
+	path := writeRawFile(t, content)
+	var stdout, stderr bytes.Buffer
+
+	for _, args := range [][]string{
+		{"-f", path, "third"},
+		{"rm", "-f", path, "-y", "1"},
+	} {
+		if code := run(args, nil, &stdout, &stderr); code != 0 {
+			t.Fatalf("run %q exited %d: %s", args, code, stderr.String())
+		}
+	}
+
+	stdout.Reset()
+	if code := run([]string{"undo", "-f", path}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("undo exited %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "- first") {
+		t.Errorf("undo output = %q, want it to show the removal of first", stdout.String())
+	}
+	data, _ := os.ReadFile(path)
+	if lines := outputLines(string(data)); len(lines) != 3 || lines[0] != "first" {
+		t.Errorf("after one undo file = %q, want first, second and third", data)
+	}
+
+	if code := run([]string{"undo", "-f", path}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("second undo exited %d: %s", code, stderr.String())
+	}
+	if data, _ := os.ReadFile(path); string(data) != content {
+		t.Errorf("after two undos file = %q, want %q", data, content)
+	}
+
+	stderr.Reset()
+	if code := run([]string{"undo", "-f", path}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("undo with no backups exited %d, want 1", code)
+	}
+	if !strings.Contains(stderr.String(), "no backups") {
+		t.Errorf("stderr = %q, want a no backups message", stderr.String())
+	}
+}
+
+func TestRun_UndoArchive(t *testing.T) {
+	t.Setenv("TODO_BACKUP_DIR", t.TempDir())
+	const content = "x 2026-10-18 2026-10-01 water plants\nopen\n"
+	const doneContent = "x 2026-10-01 older\n"
+	path := writeRawFile(t, content)
+	donePath := filepath.Join(filepath.Dir(path), "done.txt")
+	if err := os.WriteFile(donePath, []byte(doneContent), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"archive", "-f", path}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("archive exited %d: %s", code, stderr.String())
+	}
+	archived, _ := os.ReadFile(donePath)
+
+	if code := run([]string{"undo", "-f", path}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("undo exited %d: %s", code, stderr.String())
+	}
+	if data, _ := os.ReadFile(path); string(data) != content {
+		t.Errorf("todo file after undo = %q, want %q", data, content)
+	}
+	if data, _ := os.ReadFile(donePath); string(data) != doneContent {
+		t.Errorf("done file after undo = %q, want %q", data, doneContent)
+	}
+
+	if code := run([]string{"undo", "-f", path, "-redo"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("undo -redo exited %d: %s", code, stderr.String())
+	}
+	if data, _ := os.ReadFile(path); string(data) != "open\n" {
+		t.Errorf("todo file after redo = %q, want only open", data)
+	}
+	if data, _ := os.ReadFile(donePath); string(data) != string(archived) {
+		t.Errorf("done file after redo = %q, want %q", data, archived)
+	}
+}
+
+func TestRun_UndoArchiveNewDoneFile(t *testing.T) {
+	t.Setenv("TODO_BACKUP_DIR", t.TempDir())
+	path := writeRawFile(t, "x 2026-10-18 2026-10-01 water plants\n")
+	donePath := filepath.Join(filepath.Dir(path), "done.txt")
+	var stdout, stderr bytes.Buffer
+
+	for _, args := range [][]string{
+		{"archive", "-f", path},
+		{"undo", "-f", path},
+	} {
+		if code := run(args, nil, &stdout, &stderr); code != 0 {
+			t.Fatalf("run %q exited %d: %s", args, code, stderr.String())
+		}
+	}
+	if _, err := os.Stat(donePath); !os.IsNotExist(err) {
+		t.Errorf("done file after undo: %v, want it removed", err)
+	}
+
+	if code := run([]string{"undo", "-f", path, "-redo"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("undo -redo exited %d: %s", code, stderr.String())
+	}
+	if data, _ := os.ReadFile(donePath); string(data) != "x 2026-10-18 2026-10-01 water plants\n" {
+		t.Errorf("done file after redo = %q", data)
+	}
+}
+
+func TestRun_UndoRedo(t *testing.T) {
+	t.Setenv("TODO_BACKUP_DIR", t.TempDir())
+	path := writeRawFile(t, "first\n")
+	var stdout, stderr bytes.Buffer
+
+	for _, args := range [][]string{
+		{"-f", path, "second"},
+		{"-f", path, "third"},
+		{"undo", "-f", path},
+		{"undo", "-f", path},
+		{"undo", "-f", path, "-redo"},
+	} {
+		if code := run(args, nil, &stdout, &stderr); code != 0 {
+			t.Fatalf("run %q exited %d: %s", args, code, stderr.String())
+		}
+	}
+	if items := readItemsFromFile(t, path); len(items) != 2 || items[1].Message != "second" {
+		t.Errorf("after two undos and a redo items = %+v, want first and second", items)
+	}
+
+	// A new change drops the rest of the redo history.
+	if code := run([]string{"-f", path, "fourth"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	stderr.Reset()
+	if code := run([]string{"undo", "-f", path, "-redo"}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("redo after a change exited %d, want 1", code)
+	}
+	if !strings.Contains(stderr.String(), "nothing to redo") {
+		t.Errorf("stderr = %q", stderr.String())
+	}
+}
+
+func TestRun_UndoList(t *testing.T) {
+	t.Setenv("TODO_BACKUP_DIR", t.TempDir())
+	path := writeRawFile(t, "first\n")
+	var stdout, stderr bytes.Buffer
+
+	for _, args := range [][]string{
+		{"-f", path, "second"},
+		{"do", "-f", path, "1"},
+	} {
+		if code := run(args, nil, &stdout, &stderr); code != 0 {
+			t.Fatalf("run %q exited %d: %s", args, code, stderr.String())
+		}
+	}
+
+	stdout.Reset()
+	if code := run([]string{"undo", "-f", path, "-list"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("undo -list exited %d: %s", code, stderr.String())
+	}
+	var got []string
+	for _, line := range outputLines(stdout.String()) {
+		got = append(got, strings.TrimSpace(line))
+	}
+	if len(got) != 5 {
+		t.Fatalf("undo -list output = %q, want two snapshots and their changes", got)
+	}
+	// Newest first: completing item 1, then adding the second item.
+	if !strings.HasPrefix(got[0], "1 ") || got[1] != "- first" || !strings.HasPrefix(got[2], "+ x ") {
+		t.Errorf("newest snapshot = %q, want item 1 completed", got[:3])
+	}
+	if !strings.HasPrefix(got[3], "2 ") || !strings.HasSuffix(got[4], "second") {
+		t.Errorf("oldest snapshot = %q, want second added", got[3:])
+	}
+}
+
+func TestRun_BackupRotation(t *testing.T) {
+	t.Setenv("TODO_BACKUP_DIR", t.TempDir())
+	t.Setenv("TODO_BACKUPS", "2")
+	path := writeRawFile(t, "first\n")
+	var stdout, stderr bytes.Buffer
+
+	for _, text := range []string{"a", "b", "c"} {
+		if code := run([]string{"-f", path, text}, nil, &stdout, &stderr); code != 0 {
+			t.Fatalf("run exited %d: %s", code, stderr.String())
+		}
+	}
+
+	dir, err := backupDir(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshots, err := listSnapshots(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("kept %d backups, want 2", len(snapshots))
+	}
+	data, _ := os.ReadFile(snapshots[0].path)
+	if lines := outputLines(string(data)); len(lines) != 2 {
+		t.Errorf("oldest kept backup = %q, want the file before b was added", data)
+	}
+}
+
+func TestRun_BackupsDisabled(t *testing.T) {
+	t.Setenv("TODO_BACKUP_DIR", t.TempDir())
+	t.Setenv("TODO_BACKUPS", "0")
+	path := writeRawFile(t, "first\n")
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"-f", path, "second"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	if code := run([]string{"undo", "-f", path}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("undo exited %d, want 1 with backups disabled", code)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dawsonalex/todo"
+)
+
+// ANSI escape sequences used to colour listings.
+const (
+	ansiReset   = "\x1b[0m"
+	ansiDim     = "\x1b[2m"
+	ansiOverdue = "\x1b[1;31m"
+	ansiProject = "\x1b[35m"
+	ansiContext = "\x1b[36m"
+	ansiKey     = "\x1b[90m"
+	ansiHeading = "\x1b[1m"
+)
+
+// priorityColors colours items by priority; D and lower use the last.
+var priorityColors = []string{
+	"\x1b[31m", // A: red
+	"\x1b[33m", // B: yellow
+	"\x1b[32m", // C: green
+	"\x1b[34m", // D-Z: blue
+}
+
+// useColor decides from a -color flag value whether to colour output
+// written to w. "auto" colours only a terminal, and not when the NO_COLOR
+// environment variable is set or TERM is "dumb".
+func useColor(mode string, w io.Writer) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+			return false, nil
+		}
+		return isTerminal(w), nil
+	}
+	return false, fmt.Errorf("invalid -color %q: want always, never or auto", mode)
+}
+
+// isTerminal reports whether w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+// colorLine returns the todo.txt line text of item with ANSI colours: the
+// line in its priority's colour, or dimmed if done, with projects, contexts,
+// key:value pairs and an overdue due date picked out.
+func colorLine(item todo.Item, text string) string {
+	if item.Done {
+		return ansiDim + text + ansiReset
+	}
+
+	base := ""
+	if item.Priority.Valid() {
+		base = priorityColors[min(int(item.Priority-'A'), len(priorityColors)-1)]
+	}
+	overdue := item.Overdue(now())
+
+	words := strings.Split(text, " ")
+	for i, word := range words {
+		var color string
+		switch key, value, isPair := strings.Cut(word, ":"); {
+		case len(word) > 1 && word[0] == '+':
+			color = ansiProject
+		case len(word) > 1 && word[0] == '@':
+			color = ansiContext
+		case isPair && key == "due" && overdue && item.SpecialKeys[key] == value:
+			color = ansiOverdue
+		case isPair && item.SpecialKeys[key] == value && value != "":
+			color = ansiKey
+		default:
+			continue
+		}
+		words[i] = ansiReset + color + word + ansiReset + base
+	}
+	if base == "" {
+		return strings.Join(words, " ")
+	}
+	return base + strings.Join(words, " ") + ansiReset
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dawsonalex/todo"
+)
+
+func TestColorLine(t *testing.T) {
+	pinNow(t, time.Date(2026, 5, 23, 9, 0, 0, 0, time.Local))
+
+	tests := []struct {
+		line string
+		want string
+	}{
+		{
+			line: "(A) fix bug +app @work due:2026-05-20",
+			want: "\x1b[31m(A) fix bug \x1b[0m\x1b[35m+app\x1b[0m\x1b[31m \x1b[0m\x1b[36m@work\x1b[0m\x1b[31m " +
+				"\x1b[0m\x1b[1;31mdue:2026-05-20\x1b[0m\x1b[31m\x1b[0m",
+		},
+		{
+			line: "(F) tidy up est:2",
+			want: "\x1b[34m(F) tidy up \x1b[0m\x1b[90mest:2\x1b[0m\x1b[34m\x1b[0m",
+		},
+		{
+			line: "read http://example.com",
+			want: "read http://example.com",
+		},
+		{
+			line: "x 2026-05-20 (B) done +app",
+			want: "\x1b[2mx 2026-05-20 (B) done +app\x1b[0m",
+		},
+	}
+	for _, tc := range tests {
+		var item todo.Item
+		if err := item.UnmarshalText([]byte(tc.line)); err != nil {
+			t.Fatal(err)
+		}
+		if got := colorLine(item, tc.line); got != tc.want {
+			t.Errorf("colorLine(%q) = %q, want %q", tc.line, got, tc.want)
+		}
+	}
+}
+
+func TestRun_Color(t *testing.T) {
+	pinNow(t, time.Date(2026, 5, 23, 9, 0, 0, 0, time.Local))
+	path := writeRawFile(t, "(A) fix bug due:2026-05-20\n")
+
+	tests := []struct {
+		name    string
+		args    []string
+		noColor string
+		want    bool
+	}{
+		{name: "auto off a terminal", args: nil, want: false},
+		{name: "always", args: []string{"-color=always"}, want: true},
+		{name: "always despite NO_COLOR", args: []string{"-color=always"}, noColor: "1", want: true},
+		{name: "never", args: []string{"-color=never"}, want: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tc.noColor)
+			var stdout, stderr bytes.Buffer
+
+			if code := run(append([]string{"-f", path}, tc.args...), nil, &stdout, &stderr); code != 0 {
+				t.Fatalf("run exited %d: %s", code, stderr.String())
+			}
+			if got := strings.Contains(stdout.String(), "\x1b["); got != tc.want {
+				t.Errorf("coloured = %v, want %v: %q", got, tc.want, stdout.String())
+			}
+			if !strings.Contains(stdout.String(), "(overdue)") {
+				t.Errorf("output %q does not mark the item overdue", stdout.String())
+			}
+		})
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-f", path, "-color=sometimes"}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("invalid -color exited %d, want 1", code)
+	}
+}
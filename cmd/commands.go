@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dawsonalex/todo"
+)
+
+// commandFunc runs a subcommand with the arguments that follow its name.
+type commandFunc func(args []string, stdin io.Reader, stdout, stderr io.Writer) int
+
+// commands maps subcommand names to their implementations. Subcommands are
+// dispatched on the first argument, or the one after a leading -f, so
+// `todo -- do laundry` still adds an item.
+// It is filled in by init, as runList refers to it.
+var commands map[string]commandFunc
+
+func init() {
+	commands = map[string]commandFunc{
+		"do":      runDo,
+		"rm":      runRm,
+		"del":     runRm,
+		"edit":    runEdit,
+		"archive": runArchive,
+		"append":  runAppend,
+		"prepend": runPrepend,
+		"pri":     runPri,
+		"depri":   runDepri,
+		"undo":    runUndo,
+		"import":  runImport,
+		"view":    runView,
+		"report":  runReport,
+	}
+}
+
+// promptInput is where confirmation answers are read from when stdin is a
+// terminal. Tests replace it.
+var promptInput io.Reader = os.Stdin
+
+// newFlagSet returns a FlagSet for the named subcommand, pre-populated with
+// the -f flag every subcommand shares. usage is the synopsis after "todo ".
+func newFlagSet(name, usage string, stderr io.Writer) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet("todo "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(stderr, "Usage:\n  todo %s\n\nFlags:\n", usage)
+		fs.PrintDefaults()
+	}
+	filePath := fs.String("f", "", "path to todo.txt file (overrides TODO_FILE env var)")
+	return fs, filePath
+}
+
+// lockTimeout is how long a command waits for another todo process to
+// finish with the file before giving up.
+const lockTimeout = 5 * time.Second
+
+// lockFile takes the advisory lock on the todo file at path, reporting any
+// failure to stderr. unlock releases it and may be called more than once.
+// The lock file is kept with the file's backups, out of its directory.
+func lockFile(path string, stderr io.Writer) (unlock func(), ok bool) {
+	dir, err := backupDir(path)
+	var lock *todo.FileLock
+	if err == nil {
+		lock, err = todo.LockFile(filepath.Join(dir, "lock"), lockTimeout)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: locking %s: %v\n", path, err)
+		return nil, false
+	}
+	return func() { _ = lock.Unlock() }, true
+}
+
+// loadList resolves the todo file from the -f flag value, locks it and reads
+// it. The caller must call unlock once it has written any changes.
+// Failures are reported to stderr; ok is false if the caller should exit 1.
+func loadList(flagVal string, stderr io.Writer) (path string, list *todo.List, unlock func(), ok bool) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		pwd = ""
+	}
+
+	path, err = resolvePath(pwd, flagVal)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: resolving path: %v\n", err)
+		return "", nil, nil, false
+	}
+
+	unlock, ok = lockFile(path, stderr)
+	if !ok {
+		return "", nil, nil, false
+	}
+
+	list, err = todo.ReadFile(path)
+	if err != nil {
+		unlock()
+		_, _ = fmt.Fprintf(stderr, "todo: reading %s: %v\n", path, err)
+		return "", nil, nil, false
+	}
+	return path, list, unlock, true
+}
+
+// saveList writes list to path, reporting any failure to stderr. The version
+// being replaced is kept as a backup for "todo undo"; failing to keep it is
+// only a warning.
+func saveList(path string, list *todo.List, stderr io.Writer) bool {
+	return saveListWithDone(path, list, nil, stderr)
+}
+
+// saveListWithDone is saveList for a change that also appended to the done
+// file: done, the done file as it was before, is kept with the backup so
+// "todo undo" restores both files.
+func saveListWithDone(path string, list *todo.List, done *doneBackup, stderr io.Writer) bool {
+	previous, readErr := os.ReadFile(path) // #nosec G304 -- the user's todo file.
+	err := todo.WriteFile(path, list)
+	if errors.Is(err, todo.ErrConflict) {
+		_, _ = fmt.Fprintf(stderr, "todo: writing %s: %v; nothing was changed, run the command again\n", path, err)
+		return false
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: writing %s: %v\n", path, err)
+		return false
+	}
+
+	if readErr != nil {
+		// Nothing to back up if the file did not exist yet.
+		return true
+	}
+	keep, err := backupCount()
+	if err == nil {
+		err = saveBackup(path, previous, done, keep)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: warning: keeping backup of %s: %v\n", path, err)
+	}
+	return true
+}
+
+// parseIds converts item numbers as shown in a listing into Ids, checking
+// that each refers to an item in list. At least one number is required and
+// repeated numbers are returned once.
+func parseIds(list *todo.List, args []string) ([]todo.Id, error) {
+	if len(args) == 0 {
+		return nil, errors.New("no item numbers given")
+	}
+
+	ids := make([]todo.Id, 0, len(args))
+	for _, arg := range args {
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid item number %q", arg)
+		}
+		id := todo.Id(n)
+		if _, ok := list.Get(id); !ok {
+			return nil, fmt.Errorf("no item %d", n)
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// confirm writes question to w and reads a yes/no answer from promptInput.
+// Anything other than "y" or "yes" is taken as no.
+func confirm(question string, w io.Writer) bool {
+	_, _ = fmt.Fprintf(w, "%s [y/N] ", question)
+	answer, err := bufio.NewReader(promptInput).ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
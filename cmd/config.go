@@ -0,0 +1,314 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// config holds the settings read from the config file: flag defaults by
+// section, where the "" section is for the main command, a subcommand's name
+// is for that subcommand and "view.<name>" is a named view.
+type config struct {
+	path     string
+	sections map[string]map[string][]string
+}
+
+// configPath returns the config file path: TODO_CONFIG env, or else
+// config.toml in $XDG_CONFIG_HOME/todo (~/.config/todo by default).
+func configPath() (string, error) {
+	if env := os.Getenv("TODO_CONFIG"); env != "" {
+		return env, nil
+	}
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		u, err := user.Current()
+		if err != nil {
+			return "", fmt.Errorf("looking up home directory: %w", err)
+		}
+		dir = filepath.Join(u.HomeDir, ".config")
+	}
+	return filepath.Join(dir, "todo", "config.toml"), nil
+}
+
+// loadConfig reads the config file. A missing file is an empty config.
+// Values starting with "~/" are taken to be paths in the home directory.
+func loadConfig() (*config, error) {
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path) // #nosec G304 -- the user's config file.
+	if errors.Is(err, os.ErrNotExist) {
+		return &config{path: path}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sections, err := parseConfig(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	home := ""
+	if u, err := user.Current(); err == nil {
+		home = u.HomeDir
+	}
+	for _, settings := range sections {
+		for _, values := range settings {
+			for i, value := range values {
+				if rest, ok := strings.CutPrefix(value, "~/"); ok && home != "" {
+					values[i] = filepath.Join(home, rest)
+				}
+			}
+		}
+	}
+	return &config{path: path, sections: sections}, nil
+}
+
+// runView handles "todo view <name> [flags]", listing items with the flags
+// saved as the named view in the config file. Flags given after the name
+// take precedence. With no name, it prints the names of the views.
+func runView(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		cfg, err := loadConfig()
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "todo: reading config: %v\n", err)
+			return 1
+		}
+		views := cfg.views()
+		if len(views) == 0 {
+			_, _ = fmt.Fprintf(stderr, "todo: no views in %s\n", cfg.path)
+			return 1
+		}
+		for _, name := range views {
+			_, _ = fmt.Fprintln(stdout, name)
+		}
+		return 0
+	}
+	return runList(args[1:], nil, stdout, stderr, args[0])
+}
+
+// checkSections returns an error if the config has a section that is not
+// for a subcommand or a view.
+func (c *config) checkSections() error {
+	for name := range c.sections {
+		_, isCommand := commands[name]
+		if name != "" && !isCommand && !strings.HasPrefix(name, "view.") {
+			return fmt.Errorf("%s: unknown section [%s]", c.path, name)
+		}
+	}
+	return nil
+}
+
+// views returns the names of the views in the config, sorted.
+func (c *config) views() []string {
+	var names []string
+	for section := range c.sections {
+		if name, ok := strings.CutPrefix(section, "view."); ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// flagEnv maps flags to the environment variables that take precedence over
+// the config file for them.
+var flagEnv = map[string]string{"f": "TODO_FILE", "d": "DONE_FILE"}
+
+// apply sets each flag in fs that has not been set, on the command line or
+// from an earlier section, from the settings of the named section.
+func (c *config) apply(fs *flag.FlagSet, section string, settings map[string][]string) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		if fs.Lookup(key) == nil {
+			return fmt.Errorf("%s: [%s]: %s has no -%s flag", c.path, section, fs.Name(), key)
+		}
+		if set[key] || os.Getenv(flagEnv[key]) != "" {
+			continue
+		}
+		for _, value := range settings[key] {
+			if err := fs.Set(key, value); err != nil {
+				return fmt.Errorf("%s: [%s]: %s: %w", c.path, section, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// parseFlags parses args into the flags of fs, a FlagSet from newFlagSet,
+// then sets flags not given from the config file: from the subcommand's
+// section, and -f from the top of the file, so one setting picks the todo
+// file for every subcommand. Errors are reported to the FlagSet's output.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err == nil {
+		name := strings.TrimPrefix(fs.Name(), "todo ")
+		err = cfg.apply(fs, name, cfg.sections[name])
+	}
+	if err == nil {
+		if f, ok := cfg.sections[""]["f"]; ok {
+			err = cfg.apply(fs, "", map[string][]string{"f": f})
+		}
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(fs.Output(), "todo: reading config: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// parseConfig parses the subset of TOML the config file uses: [section]
+// headers, and key = value lines where a value is a string, a boolean, a
+// number or a one-line array of those. Values are returned as the text to
+// give the flag, one per array element.
+func parseConfig(text string) (map[string]map[string][]string, error) {
+	sections := map[string]map[string][]string{"": {}}
+	section := ""
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			header, rest, ok := strings.Cut(line[1:], "]")
+			if !ok || !isComment(rest) {
+				return nil, fmt.Errorf("line %d: invalid section header", n+1)
+			}
+			section = strings.TrimSpace(header)
+			if _, dup := sections[section]; dup || section == "" {
+				return nil, fmt.Errorf("line %d: duplicate or empty section [%s]", n+1, section)
+			}
+			sections[section] = map[string][]string{}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: want key = value", n+1)
+		}
+		key = strings.TrimSpace(key)
+		if unquoted, err := strconv.Unquote(key); err == nil {
+			key = unquoted
+		}
+		if key == "" {
+			return nil, fmt.Errorf("line %d: missing key", n+1)
+		}
+		if _, dup := sections[section][key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", n+1, key)
+		}
+
+		values, err := parseConfigValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", n+1, key, err)
+		}
+		sections[section][key] = values
+	}
+	return sections, nil
+}
+
+// parseConfigValue parses a value and any comment after it. An array gives
+// one value per element.
+func parseConfigValue(s string) ([]string, error) {
+	if !strings.HasPrefix(s, "[") {
+		value, rest, err := parseConfigScalar(s)
+		if err != nil {
+			return nil, err
+		}
+		if !isComment(rest) {
+			return nil, fmt.Errorf("unexpected %q after value", rest)
+		}
+		return []string{value}, nil
+	}
+
+	values := []string{}
+	rest := strings.TrimSpace(s[1:])
+	for !strings.HasPrefix(rest, "]") {
+		value, after, err := parseConfigScalar(rest)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		after = strings.TrimSpace(after)
+		if next, ok := strings.CutPrefix(after, ","); ok {
+			rest = strings.TrimSpace(next)
+			continue
+		}
+		if !strings.HasPrefix(after, "]") {
+			return nil, errors.New("arrays must be on one line, with elements separated by commas")
+		}
+		rest = after
+	}
+	if !isComment(rest[1:]) {
+		return nil, fmt.Errorf("unexpected %q after array", rest[1:])
+	}
+	return values, nil
+}
+
+// parseConfigScalar parses a string, boolean or number from the start of s,
+// returning its text and what follows it.
+func parseConfigScalar(s string) (value, rest string, err error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\\' {
+				i++
+				continue
+			}
+			if s[i] == '"' {
+				value, err := strconv.Unquote(s[:i+1])
+				if err != nil {
+					return "", "", fmt.Errorf("invalid string %s", s[:i+1])
+				}
+				return value, s[i+1:], nil
+			}
+		}
+		return "", "", errors.New("missing closing quote")
+	case strings.HasPrefix(s, "'"):
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return "", "", errors.New("missing closing quote")
+		}
+		return s[1 : end+1], s[end+2:], nil
+	}
+
+	end := strings.IndexAny(s, " \t,]#")
+	if end < 0 {
+		end = len(s)
+	}
+	value, rest = s[:end], s[end:]
+	if value == "true" || value == "false" {
+		return value, rest, nil
+	}
+	if _, err := strconv.ParseFloat(strings.ReplaceAll(value, "_", ""), 64); err == nil {
+		return strings.ReplaceAll(value, "_", ""), rest, nil
+	}
+	return "", "", fmt.Errorf("invalid value %q: quote strings", value)
+}
+
+// isComment reports whether s is empty or only a comment.
+func isComment(s string) bool {
+	s = strings.TrimSpace(s)
+	return s == "" || s[0] == '#'
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes a config file and points TODO_CONFIG at it for the
+// duration of the test.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("writeConfig: %v", err)
+	}
+	t.Setenv("TODO_CONFIG", path)
+	return path
+}
+
+func TestParseConfig(t *testing.T) {
+	const text = `# defaults
+s = "priority"   # sort
+q = ["@work", 'not +home']
+done = true
+"raw" = false
+
+[view.today]
+q = "due<=today or pri=A"
+format = "{{.ID}}\t{{.Message}}"
+
+[rm]
+y = true
+`
+	got, err := parseConfig(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string][]string{
+		"":           {"s": {"priority"}, "q": {"@work", "not +home"}, "done": {"true"}, "raw": {"false"}},
+		"view.today": {"q": {"due<=today or pri=A"}, "format": {"{{.ID}}\t{{.Message}}"}},
+		"rm":         {"y": {"true"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestParseConfig_Invalid(t *testing.T) {
+	for _, text := range []string{
+		"s priority",
+		"s = priority",
+		`s = "priority`,
+		`s = "a" "b"`,
+		`q = ["a",`,
+		`q = ["a"] x`,
+		"[view.x\nq = \"a\"",
+		"[rm]\n[rm]",
+		"s = \"a\"\ns = \"b\"",
+		` = "a"`,
+	} {
+		if got, err := parseConfig(text); err == nil {
+			t.Errorf("parseConfig(%q) = %q, want error", text, got)
+		}
+	}
+}
+
+func TestRun_ConfigDefaults(t *testing.T) {
+	path := writeRawFile(t, "(B) beta @work\n(A) alpha @home\n(C) gamma @work\n")
+	writeConfig(t, "f = \""+path+"\"\ns = \"priority\"\nraw = true\nq = \"@work\"\n")
+	var stdout, stderr bytes.Buffer
+
+	if code := run(nil, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	want := []string{"(B) beta @work", "(C) gamma @work"}
+	if got := outputLines(stdout.String()); !sliceEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Flags on the command line take precedence, repeatable ones included.
+	stdout.Reset()
+	if code := run([]string{"-q", "@home", "-raw=false"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	want = []string{"2 (A) alpha @home"}
+	if got := outputLines(stdout.String()); !sliceEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// TODO_FILE takes precedence over the file setting.
+	t.Setenv("TODO_FILE", emptyFilePath(t))
+	stdout.Reset()
+	if code := run(nil, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	if got := stdout.String(); got != "" {
+		t.Errorf("with TODO_FILE set got %q, want nothing", got)
+	}
+	t.Setenv("TODO_FILE", "")
+
+	// The file setting applies to subcommands too.
+	stdout.Reset()
+	if code := run([]string{"do", "1"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("do exited %d: %s", code, stderr.String())
+	}
+	if items := readItemsFromFile(t, path); !items[0].Done {
+		t.Error("do did not use the configured file")
+	}
+}
+
+func TestRun_ConfigSubcommandSection(t *testing.T) {
+	path := writeRawFile(t, "first\nsecond\n")
+	writeConfig(t, "[rm]\ny = true\n")
+	var stdout, stderr bytes.Buffer
+
+	// With y set, rm does not ask, even though stdin is not a terminal.
+	answerPrompt(t, "n\n")
+	if code := run([]string{"rm", "-f", path, "1"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("rm exited %d: %s", code, stderr.String())
+	}
+	if items := readItemsFromFile(t, path); len(items) != 1 {
+		t.Errorf("got %d items, want 1", len(items))
+	}
+}
+
+func TestRun_View(t *testing.T) {
+	path := writeRawFile(t, "(B) beta @work\n(A) alpha @home\n(C) gamma @work\n")
+	writeConfig(t, `f = "`+path+`"
+raw = true
+
+[view.work]
+q = "@work"
+s = "priority"
+format = "{{.Priority}} {{.Message}}"
+
+[view.all]
+done = true
+`)
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"view", "work"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("view exited %d: %s", code, stderr.String())
+	}
+	want := []string{"B beta @work", "C gamma @work"}
+	if got := outputLines(stdout.String()); !sliceEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"view", "work", "-s", "created", "-format", "{{.Message}}"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("view exited %d: %s", code, stderr.String())
+	}
+	want = []string{"beta @work", "gamma @work"}
+	if got := outputLines(stdout.String()); !sliceEqual(got, want) {
+		t.Errorf("with flags got %q, want %q", got, want)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"view"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("view exited %d: %s", code, stderr.String())
+	}
+	if got := outputLines(stdout.String()); !sliceEqual(got, []string{"all", "work"}) {
+		t.Errorf("view names = %q", got)
+	}
+
+	for _, args := range [][]string{{"view", "nope"}, {"view", "work", "add", "this"}} {
+		stderr.Reset()
+		if code := run(args, nil, &stdout, &stderr); code != 1 {
+			t.Errorf("run %q exited %d, want 1", args, code)
+		}
+	}
+	if items := readItemsFromFile(t, path); len(items) != 3 {
+		t.Errorf("file has %d items, want 3", len(items))
+	}
+}
+
+func TestRun_ConfigInvalid(t *testing.T) {
+	path := writeRawFile(t, "first\n")
+
+	tests := []struct {
+		content string
+		args    []string
+	}{
+		{"nope = true\n", []string{"-f", path}},
+		{"s = 1\n[do]\nq = \"x\"\n", []string{"do", "-f", path, "1"}},
+		{"[bogus]\n", []string{"do", "-f", path, "1"}},
+		{"s = \n", []string{"do", "-f", path, "1"}},
+	}
+	for _, tc := range tests {
+		writeConfig(t, tc.content)
+		var stdout, stderr bytes.Buffer
+		if code := run(tc.args, nil, &stdout, &stderr); code != 1 {
+			t.Errorf("config %q: exited %d, want 1", tc.content, code)
+		}
+		if !strings.Contains(stderr.String(), "config") {
+			t.Errorf("config %q: stderr = %q, want a config error", tc.content, stderr.String())
+		}
+	}
+	if items := readItemsFromFile(t, path); items[0].Done {
+		t.Error("item was completed despite the config error")
+	}
+}
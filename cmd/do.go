@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/dawsonalex/todo"
+)
+
+// runDo handles "todo do <n>...", marking each numbered item as done today.
+// Every number is validated before anything is changed, so a typo in one
+// argument leaves the file untouched. Completing an item with a rec: key
+// adds its next occurrence to the end of the file.
+func runDo(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	fs, filePath := newFlagSet("do", "do [flags] <n>...", stderr)
+	if err := parseFlags(fs, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+
+	path, list, unlock, ok := loadList(*filePath, stderr)
+	if !ok {
+		return 1
+	}
+	defer unlock()
+
+	ids, err := parseIds(list, fs.Args())
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: %v\n", err)
+		return 1
+	}
+
+	completed := today()
+	var added []todo.Id
+	for _, id := range ids {
+		item, _ := list.Get(id)
+		if item.Done {
+			_, _ = fmt.Fprintf(stderr, "todo: item %d is already done\n", id)
+			continue
+		}
+		next, err := list.Complete(id, completed)
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "todo: %v\n", err)
+			return 1
+		}
+		if next != 0 {
+			added = append(added, next)
+		}
+	}
+
+	if !saveList(path, list, stderr) {
+		return 1
+	}
+
+	for _, id := range append(ids, added...) {
+		item, _ := list.Get(id)
+		text, _ := item.MarshalText()
+		_, _ = fmt.Fprintf(stdout, "%d %s\n", id, text)
+	}
+	return 0
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pinNow fixes the clock used by the CLI for the duration of the test.
+func pinNow(t *testing.T, at time.Time) {
+	t.Helper()
+	prev := now
+	now = func() time.Time { return at }
+	t.Cleanup(func() { now = prev })
+}
+
+func TestRun_Do(t *testing.T) {
+	pinNow(t, time.Date(2026, 5, 23, 9, 30, 0, 0, time.Local))
+	path := writeRawFile(t, "2026-05-01 first\n2026-05-02 second\n2026-05-03 third\n")
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"do", "-f", path, "1", "3"}, nil, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+
+	items := readItemsFromFile(t, path)
+	if len(items) != 3 {
+		t.Fatalf("want 3 items, got %d", len(items))
+	}
+	wantDone := []bool{true, false, true}
+	completed := time.Date(2026, 5, 23, 0, 0, 0, 0, time.Local)
+	for i, item := range items {
+		if item.Done != wantDone[i] {
+			t.Errorf("item %d done = %v, want %v", i, item.Done, wantDone[i])
+		}
+		if item.Done && !item.CompletedDate.Equal(completed) {
+			t.Errorf("item %d completed = %v, want %v", i, item.CompletedDate, completed)
+		}
+	}
+
+	lines := outputLines(stdout.String())
+	if len(lines) != 2 {
+		t.Fatalf("want 2 lines, got %d: %v", len(lines), lines)
+	}
+	if lines[0] != "1 x 2026-05-23 2026-05-01 first" {
+		t.Errorf("line 0 = %q", lines[0])
+	}
+}
+
+func TestRun_DoInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"out of range", []string{"2", "5"}, "no item 5"},
+		{"zero", []string{"0"}, "no item 0"},
+		{"not a number", []string{"one"}, `invalid item number "one"`},
+		{"no numbers", nil, "no item numbers given"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			const content = "first\nsecond\n"
+			path := writeRawFile(t, content)
+			var stdout, stderr bytes.Buffer
+
+			args := append([]string{"do", "-f", path}, tc.args...)
+			if code := run(args, nil, &stdout, &stderr); code != 1 {
+				t.Fatalf("run exited %d, want 1", code)
+			}
+			if !strings.Contains(stderr.String(), tc.wantErr) {
+				t.Errorf("stderr = %q, want it to contain %q", stderr.String(), tc.wantErr)
+			}
+			for i, item := range readItemsFromFile(t, path) {
+				if item.Done {
+					t.Errorf("item %d was completed despite the error", i)
+				}
+			}
+		})
+	}
+}
+
+// TestRun_DoUsesLineNumbers checks that item numbers refer to file lines, so
+// blank lines do not shift the numbering.
+func TestRun_DoUsesLineNumbers(t *testing.T) {
+	path := writeRawFile(t, "first\n\nthird\n")
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"do", "-f", path, "3"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	for _, item := range readItemsFromFile(t, path) {
+		if item.Done != (item.Message == "third") {
+			t.Errorf("%q done = %v", item.Message, item.Done)
+		}
+	}
+
+	stderr.Reset()
+	if code := run([]string{"do", "-f", path, "2"}, nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("run on a blank line exited %d, want 1", code)
+	}
+}
+
+func TestRun_DoRecurring(t *testing.T) {
+	pinNow(t, time.Date(2026, 5, 23, 9, 30, 0, 0, time.Local))
+	path := writeRawFile(t, "2026-05-01 pay rent due:2026-05-01 rec:+1m\nonce\n")
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"do", "-f", path, "1", "2"}, nil, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+
+	want := []string{
+		"1 x 2026-05-23 2026-05-01 pay rent due:2026-05-01 rec:+1m",
+		"2 x 2026-05-23 once",
+		"3 2026-05-23 pay rent due:2026-06-01 rec:+1m",
+	}
+	if got := outputLines(stdout.String()); !sliceEqual(got, want) {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	if items := readItemsFromFile(t, path); len(items) != 3 || items[2].Done {
+		t.Errorf("file items = %+v, want the next occurrence added open", items)
+	}
+}
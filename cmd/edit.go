@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/dawsonalex/todo"
+)
+
+// runEdit handles "todo edit <n> [text...]". With text, the item is replaced
+// by the parsed text; without, the item's line is opened in $EDITOR. Either
+// way the original creation date is kept if the new text does not give one.
+func runEdit(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	fs, filePath := newFlagSet("edit", "edit [flags] <n> [text...]", stderr)
+	if err := parseFlags(fs, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+
+	path, list, unlock, ok := loadList(*filePath, stderr)
+	if !ok {
+		return 1
+	}
+	defer unlock()
+
+	posArgs := fs.Args()
+	if len(posArgs) == 0 {
+		_, _ = fmt.Fprintln(stderr, "todo: no item number given")
+		return 1
+	}
+	ids, err := parseIds(list, posArgs[:1])
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: %v\n", err)
+		return 1
+	}
+	id := ids[0]
+	old, _ := list.Get(id)
+
+	var text string
+	if len(posArgs) > 1 {
+		text = strings.Join(posArgs[1:], " ")
+	} else {
+		oldText, _ := old.MarshalText()
+		// Don't hold the lock while the editor is open. The file is read
+		// again afterwards, and the edit applied only if the item is still
+		// the one that was opened.
+		unlock()
+		text, err = editText(string(oldText), stderr)
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "todo: editing item %d: %v\n", id, err)
+			return 1
+		}
+		if text == string(oldText) {
+			_, _ = fmt.Fprintf(stderr, "todo: item %d unchanged\n", id)
+			return 0
+		}
+		if unlock, ok = lockFile(path, stderr); !ok {
+			_, _ = fmt.Fprintf(stderr, "todo: edited text not saved: %s\n", text)
+			return 1
+		}
+		defer unlock()
+		if list, err = todo.ReadFile(path); err != nil {
+			_, _ = fmt.Fprintf(stderr, "todo: reading %s: %v\n", path, err)
+			_, _ = fmt.Fprintf(stderr, "todo: edited text not saved: %s\n", text)
+			return 1
+		}
+		current, found := list.Get(id)
+		currentText, _ := current.MarshalText()
+		if !found || string(currentText) != string(oldText) {
+			_, _ = fmt.Fprintf(stderr, "todo: item %d changed while it was being edited\n", id)
+			_, _ = fmt.Fprintf(stderr, "todo: edited text not saved: %s\n", text)
+			return 1
+		}
+	}
+
+	var item todo.Item
+	if err := item.UnmarshalText([]byte(text)); err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: parsing item %q: %v\n", text, err)
+		return 1
+	}
+	if item.CreatedDate.IsZero() {
+		item.CreatedDate = old.CreatedDate
+	}
+	item.ResolveDates(now())
+	if err := list.Set(id, item); err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: %v\n", err)
+		return 1
+	}
+
+	if !saveList(path, list, stderr) {
+		return 1
+	}
+
+	item, _ = list.Get(id)
+	printItems([]todo.Item{item}, stdout, true, false)
+	return 0
+}
+
+// editText opens text in the user's $EDITOR (vi if unset) and returns the
+// first non-blank line of the result.
+func editText(text string, stderr io.Writer) (string, error) {
+	f, err := os.CreateTemp("", "todo-*.txt")
+	if err != nil {
+		return "", err
+	}
+	tmpPath := f.Name()
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	_, writeErr := fmt.Fprintf(f, "%s\n", text)
+	if closeErr := f.Close(); closeErr != nil && writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr != nil {
+		return "", writeErr
+	}
+
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+	}
+	// #nosec G204 -- the editor command is chosen by the user.
+	cmd := exec.Command(editor[0], append(editor[1:], tmpPath)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("running %s: %w", editor[0], err)
+	}
+
+	data, err := os.ReadFile(tmpPath) // #nosec G304 -- our own temp file.
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line, nil
+		}
+	}
+	return "", errors.New("edited text is empty")
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun_EditText(t *testing.T) {
+	path := writeRawFile(t, "2024-01-01 first\n2024-01-02 second\n")
+
+	tests := []struct {
+		name        string
+		text        []string
+		wantMessage string
+		wantCreated time.Time
+	}{
+		{"keeps creation date", []string{"(A)", "new", "text", "+proj"}, "new text +proj", time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local)},
+		{"explicit creation date", []string{"2024-03-04 dated"}, "dated", time.Date(2024, 3, 4, 0, 0, 0, 0, time.Local)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"edit", "-f", path, "2"}, tc.text...)
+			if code := run(args, nil, &stdout, &stderr); code != 0 {
+				t.Fatalf("run exited %d: %s", code, stderr.String())
+			}
+
+			items := readItemsFromFile(t, path)
+			if len(items) != 2 {
+				t.Fatalf("want 2 items, got %d", len(items))
+			}
+			if items[0].Message != "first" {
+				t.Errorf("item 1 changed to %q", items[0].Message)
+			}
+			got := items[1]
+			if got.Message != tc.wantMessage || !got.CreatedDate.Equal(tc.wantCreated) {
+				t.Errorf("item 2 = %q created %v, want %q created %v",
+					got.Message, got.CreatedDate, tc.wantMessage, tc.wantCreated)
+			}
+		})
+	}
+}
+
+func TestRun_EditInEditor(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("editor stub is a shell script")
+	}
+	// The stub editor rewrites the file it is given, as a user would.
+	editor := filepath.Join(t.TempDir(), "editor.sh")
+	script := "#!/bin/sh\nsed 's/milk/bread/' \"$1\" > \"$1.new\" && mv \"$1.new\" \"$1\"\n"
+	if err := os.WriteFile(editor, []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EDITOR", editor)
+
+	path := writeRawFile(t, "2024-01-01 buy milk @shop\n")
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"edit", "-f", path, "1"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+
+	items := readItemsFromFile(t, path)
+	if len(items) != 1 || items[0].Message != "buy bread @shop" {
+		t.Fatalf("items = %+v, want buy bread @shop", items)
+	}
+	if !strings.Contains(stdout.String(), "1 2024-01-01 buy bread @shop") {
+		t.Errorf("stdout = %q, want edited item", stdout.String())
+	}
+}
+
+func TestRun_EditInvalid(t *testing.T) {
+	path := writeRawFile(t, "first\n")
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"edit", "-f", path, "2", "text"}, nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("run exited %d, want 1", code)
+	}
+	if !strings.Contains(stderr.String(), "no item 2") {
+		t.Errorf("stderr = %q, want it to mention item 2", stderr.String())
+	}
+}
+
+// TestRun_EditConcurrentChange checks that a change made elsewhere in the
+// file while the editor is open is kept along with the edit.
+func TestRun_EditConcurrentChange(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("editor stub is a shell script")
+	}
+	path := writeRawFile(t, "buy milk\n")
+	editor := filepath.Join(t.TempDir(), "editor.sh")
+	script := "#!/bin/sh\necho 'buy bread' > \"$1\"\necho 'added elsewhere' >> '" + path + "'\n"
+	if err := os.WriteFile(editor, []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EDITOR", editor)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"edit", "-f", path, "1"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "buy bread\nadded elsewhere\n" {
+		t.Errorf("file = %q, want the edit and the concurrent change", data)
+	}
+}
+
+// TestRun_EditConflict checks that when the item itself changes while the
+// editor is open, the change is kept and the edited text is printed.
+func TestRun_EditConflict(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("editor stub is a shell script")
+	}
+	path := writeRawFile(t, "buy milk\n")
+	editor := filepath.Join(t.TempDir(), "editor.sh")
+	script := "#!/bin/sh\necho 'buy bread' > \"$1\"\necho 'buy eggs' > '" + path + "'\n"
+	if err := os.WriteFile(editor, []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EDITOR", editor)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"edit", "-f", path, "1"}, nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("run exited %d, want 1", code)
+	}
+	if !strings.Contains(stderr.String(), "edited text not saved: buy bread") {
+		t.Errorf("stderr = %q, want the edited text", stderr.String())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "buy eggs\n" {
+		t.Errorf("file = %q, want the concurrent change kept", data)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dawsonalex/todo"
+)
+
+// dateLayout is the todo.txt date format.
+const dateLayout = "2006-01-02"
+
+// templateFuncs are the helpers available to -format templates.
+var templateFuncs = template.FuncMap{
+	// date formats a date as YYYY-MM-DD, or "" if it is unset.
+	"date": func(t time.Time) string { return formatDate(dateLayout, t) },
+	// datefmt formats a date with a Go time layout, or "" if it is unset.
+	"datefmt": formatDate,
+	// pad pads s with spaces on the right to width n, or on the left if n is
+	// negative.
+	"pad": func(n int, v any) string {
+		s := fmt.Sprint(v)
+		width := utf8.RuneCountInString(s)
+		if n < 0 {
+			return strings.Repeat(" ", max(0, -n-width)) + s
+		}
+		return s + strings.Repeat(" ", max(0, n-width))
+	},
+	// join joins a list of strings with sep.
+	"join": func(sep string, list []string) string { return strings.Join(list, sep) },
+	// projects returns the item's projects as "+a +b".
+	"projects": func(item *todo.Item) string { return prefixAll("+", item.Projects) },
+	// contexts returns the item's contexts as "@a @b".
+	"contexts": func(item *todo.Item) string { return prefixAll("@", item.Contexts) },
+	// key returns the value of the item's key:value pair, or "".
+	"key": func(name string, item *todo.Item) string { return item.SpecialKeys[name] },
+	// due and threshold return the item's due: and t: dates, unset if it
+	// has none.
+	"due": func(item *todo.Item) time.Time {
+		date, _ := item.Due()
+		return date
+	},
+	"threshold": func(item *todo.Item) time.Time {
+		date, _ := item.Threshold()
+		return date
+	},
+	// overdue reports whether the item is open and past its due date.
+	"overdue": func(item *todo.Item) bool { return item.Overdue(now()) },
+	// line returns the item as a todo.txt line.
+	"line": func(item *todo.Item) string {
+		text, _ := item.MarshalText()
+		return string(text)
+	},
+}
+
+// formatDate formats t with layout, or returns "" if t is unset.
+func formatDate(layout string, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(layout)
+}
+
+// prefixAll returns the words joined with spaces, each after prefix.
+func prefixAll(prefix string, words []string) string {
+	var b strings.Builder
+	for i, word := range words {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(prefix + word)
+	}
+	return b.String()
+}
+
+// parseFormat parses a -format template.
+func parseFormat(text string) (*template.Template, error) {
+	return template.New("format").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+}
+
+// printTemplate writes each item to w by executing tmpl on it, followed by
+// a newline.
+func printTemplate(items []todo.Item, tmpl *template.Template, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for i := range items {
+		if err := tmpl.Execute(bw, &items[i]); err != nil {
+			return err
+		}
+		if err := bw.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
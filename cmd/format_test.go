@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun_Format(t *testing.T) {
+	pinNow(t, time.Date(2026, 5, 23, 9, 0, 0, 0, time.Local))
+	const content = "(A) 2026-05-01 pay rent +home +money @desk due:2026-05-20\nplain\n"
+
+	tests := []struct {
+		name   string
+		format string
+		want   []string
+	}{
+		{
+			name:   "fields",
+			format: "{{.ID}} {{.Priority}} {{.Message}}",
+			want:   []string{"1 A pay rent +home +money @desk due:2026-05-20", "2  plain"},
+		},
+		{
+			name:   "tab separated",
+			format: "{{.ID}}{{\"\\t\"}}{{line .}}",
+			want:   []string{"1\t(A) 2026-05-01 pay rent +home +money @desk due:2026-05-20", "2\tplain"},
+		},
+		{
+			name:   "dates",
+			format: "{{date .CreatedDate}}|{{datefmt \"Jan 2\" (due .)}}|{{overdue .}}",
+			want:   []string{"2026-05-01|May 20|true", "||false"},
+		},
+		{
+			name:   "padding",
+			format: "[{{pad -3 .ID}}] [{{pad 6 .Priority}}]",
+			want:   []string{"[  1] [A     ]", "[  2] [      ]"},
+		},
+		{
+			name:   "tags and keys",
+			format: "{{projects .}}|{{contexts .}}|{{.Projects | join \",\"}}|{{key \"due\" .}}|{{.SpecialKeys.due}}",
+			want:   []string{"+home +money|@desk|home,money|2026-05-20|2026-05-20", "||||"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeRawFile(t, content)
+			var stdout, stderr bytes.Buffer
+
+			if code := run([]string{"-f", path, "-format", tc.format}, nil, &stdout, &stderr); code != 0 {
+				t.Fatalf("run exited %d: %s", code, stderr.String())
+			}
+			got := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+			if !sliceEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRun_FormatInvalid(t *testing.T) {
+	path := writeRawFile(t, "plain\n")
+
+	for _, args := range [][]string{
+		{"-format", "{{.ID"},
+		{"-format", "{{.Nope}}"},
+		{"-format", "{{.ID}}", "-o", "json"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(append([]string{"-f", path}, args...), nil, &stdout, &stderr); code != 1 {
+			t.Errorf("run %q exited %d, want 1", args, code)
+		}
+	}
+}
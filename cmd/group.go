@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/dawsonalex/todo"
+)
+
+// noGroup is the heading of the group of items that have no value for the
+// -group field.
+const noGroup = "(none)"
+
+// group is a heading of a -group listing and the items under it.
+type group struct {
+	heading string
+	items   []todo.Item
+}
+
+// groupFields are the fields -group accepts. Each returns the headings an
+// item is listed under, in the order the groups are printed.
+var groupFields = map[string]func(item *todo.Item) []string{
+	"project": func(item *todo.Item) []string { return tagHeadings("+", item.Projects) },
+	"context": func(item *todo.Item) []string { return tagHeadings("@", item.Contexts) },
+	"priority": func(item *todo.Item) []string {
+		if !item.Priority.Valid() {
+			return nil
+		}
+		return []string{"(" + item.Priority.String() + ")"}
+	},
+	"due": func(item *todo.Item) []string {
+		date, ok := item.Due()
+		if !ok {
+			return nil
+		}
+		return []string{"due:" + date.Format(dateLayout)}
+	},
+}
+
+// tagHeadings returns the headings for tags, each with prefix and without
+// duplicates.
+func tagHeadings(prefix string, tags []string) []string {
+	var headings []string
+	for _, tag := range tags {
+		if heading := prefix + tag; !slices.Contains(headings, heading) {
+			headings = append(headings, heading)
+		}
+	}
+	return headings
+}
+
+// groupItems splits items into groups by field, keeping their order within
+// each group. An item with several projects or contexts is in the group for
+// each; tags that differ only in case share the group of the first listed.
+// Groups are ordered by heading, ignoring case, with items that have
+// no value for the field last under noGroup.
+func groupItems(items []todo.Item, field string) ([]group, error) {
+	headings, ok := groupFields[field]
+	if !ok {
+		return nil, fmt.Errorf("unknown group field %q: want project, context, priority or due", field)
+	}
+
+	var groups []group
+	index := make(map[string]int)
+	var none []todo.Item
+	for _, item := range items {
+		names := headings(&item)
+		if len(names) == 0 {
+			none = append(none, item)
+			continue
+		}
+		for _, name := range names {
+			key := strings.ToLower(name)
+			i, ok := index[key]
+			if !ok {
+				i = len(groups)
+				index[key] = i
+				groups = append(groups, group{heading: name})
+			}
+			groups[i].items = append(groups[i].items, item)
+		}
+	}
+	slices.SortFunc(groups, func(a, b group) int {
+		return strings.Compare(strings.ToLower(a.heading), strings.ToLower(b.heading))
+	})
+	if len(none) > 0 {
+		groups = append(groups, group{heading: noGroup, items: none})
+	}
+	return groups, nil
+}
+
+// printGroups writes each group's heading followed by its items, as
+// printItems or, if tmpl is not nil, printTemplate would, with a blank line
+// between groups. Item numbers are padded to the same width in every group.
+func printGroups(groups []group, w io.Writer, tmpl *template.Template, numbered, color bool) error {
+	width := 0
+	if numbered {
+		for _, g := range groups {
+			width = max(width, idWidth(g.items))
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	for i, g := range groups {
+		if i > 0 {
+			_, _ = fmt.Fprintln(bw)
+		}
+		heading := g.heading
+		if color {
+			heading = ansiHeading + heading + ansiReset
+		}
+		_, _ = fmt.Fprintln(bw, heading)
+		if tmpl != nil {
+			if err := printTemplate(g.items, tmpl, bw); err != nil {
+				return err
+			}
+			continue
+		}
+		writeItems(bw, g.items, width, numbered, color)
+	}
+	return bw.Flush()
+}
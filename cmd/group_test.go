@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun_Group(t *testing.T) {
+	const content = "(B) 2026-01-03 beta +web due:2026-06-01\n" +
+		"(A) 2026-01-02 alpha +api @desk\n" +
+		"(B) 2026-01-01 gamma +Web +api due:2026-05-01\n" +
+		"2026-01-04 delta @desk\n"
+
+	// Sorted by -s within each group.
+	tests := []struct {
+		group string
+		want  []string
+	}{
+		{"project", []string{
+			"+api", "3 (B) 2026-01-01 gamma +Web +api due:2026-05-01", "2 (A) 2026-01-02 alpha +api @desk", "",
+			"+Web", "3 (B) 2026-01-01 gamma +Web +api due:2026-05-01", "1 (B) 2026-01-03 beta +web due:2026-06-01", "",
+			"(none)", "4 2026-01-04 delta @desk",
+		}},
+		{"context", []string{
+			"@desk", "4 2026-01-04 delta @desk", "2 (A) 2026-01-02 alpha +api @desk", "",
+			"(none)", "3 (B) 2026-01-01 gamma +Web +api due:2026-05-01", "1 (B) 2026-01-03 beta +web due:2026-06-01",
+		}},
+		{"priority", []string{
+			"(A)", "2 (A) 2026-01-02 alpha +api @desk", "",
+			"(B)", "3 (B) 2026-01-01 gamma +Web +api due:2026-05-01", "1 (B) 2026-01-03 beta +web due:2026-06-01", "",
+			"(none)", "4 2026-01-04 delta @desk",
+		}},
+		{"due", []string{
+			"due:2026-05-01", "3 (B) 2026-01-01 gamma +Web +api due:2026-05-01", "",
+			"due:2026-06-01", "1 (B) 2026-01-03 beta +web due:2026-06-01", "",
+			"(none)", "4 2026-01-04 delta @desk", "2 (A) 2026-01-02 alpha +api @desk",
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.group, func(t *testing.T) {
+			pinNow(t, time.Date(2026, 1, 10, 0, 0, 0, 0, time.Local))
+			path := writeRawFile(t, content)
+			var stdout, stderr bytes.Buffer
+
+			if code := run([]string{"-f", path, "-group", tc.group, "-s", "-id"}, nil, &stdout, &stderr); code != 0 {
+				t.Fatalf("run exited %d: %s", code, stderr.String())
+			}
+			got := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+			if !sliceEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRun_GroupFormat(t *testing.T) {
+	path := writeRawFile(t, "alpha +a\nbeta +b\n")
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"-f", path, "-group", "project", "-format", "- {{.Message}}"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	if want := "+a\n- alpha +a\n\n+b\n- beta +b\n"; stdout.String() != want {
+		t.Errorf("got %q, want %q", stdout.String(), want)
+	}
+}
+
+func TestRun_GroupErrors(t *testing.T) {
+	path := writeRawFile(t, "first\n")
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-group", "tag"}, "unknown group field"},
+		{[]string{"-group", "project", "-o", "json"}, "-group cannot be used"},
+	}
+	for _, tc := range tests {
+		var stdout, stderr bytes.Buffer
+		if code := run(append([]string{"-f", path}, tc.args...), nil, &stdout, &stderr); code != 1 {
+			t.Errorf("%q exited %d, want 1", tc.args, code)
+		}
+		if !strings.Contains(stderr.String(), tc.want) {
+			t.Errorf("%q: stderr = %q, want %q", tc.args, stderr.String(), tc.want)
+		}
+	}
+}
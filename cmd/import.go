@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dawsonalex/todo"
+)
+
+// runImport handles "todo import [-format todo|json] [file]", adding the
+// items in file, or piped to stdin, to the end of the todo file. JSON input
+// is either an array of items or one item per line, in the shape written by
+// "todo -o json" and "todo -o jsonl". Ids in the input are ignored: imported
+// items are numbered as new items. As when adding, open items without a
+// creation date are created today.
+func runImport(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs, filePath := newFlagSet("import", "import [flags] [file]", stderr)
+	format := fs.String("format", "todo", "input format: todo (todo.txt lines) or json (an array or one object per line)")
+	if err := parseFlags(fs, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+	if *format != "todo" && *format != "json" {
+		_, _ = fmt.Fprintf(stderr, "todo: unknown input format %q: want todo or json\n", *format)
+		return 1
+	}
+
+	var input io.Reader
+	switch posArgs := fs.Args(); {
+	case len(posArgs) > 1:
+		fs.Usage()
+		return 1
+	case len(posArgs) == 1 && posArgs[0] != "-":
+		f, err := os.Open(posArgs[0])
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "todo: %v\n", err)
+			return 1
+		}
+		defer func() { _ = f.Close() }()
+		input = f
+	case stdin != nil:
+		input = stdin
+	default:
+		_, _ = fmt.Fprintln(stderr, "todo: nothing to import: give a file or pipe items to stdin")
+		return 1
+	}
+
+	var items []todo.Item
+	var err error
+	if *format == "json" {
+		items, err = readJSONItems(input)
+	} else {
+		items, err = readTodoItems(input)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: reading items: %v\n", err)
+		return 1
+	}
+
+	path, list, unlock, ok := loadList(*filePath, stderr)
+	if !ok {
+		return 1
+	}
+	defer unlock()
+
+	for _, item := range items {
+		item.ID = 0
+		if !item.Done && item.CreatedDate.IsZero() {
+			item.CreatedDate = today()
+		}
+		item.ResolveDates(now())
+		list.Add(item)
+	}
+	if len(items) > 0 && !saveList(path, list, stderr) {
+		return 1
+	}
+
+	_, _ = fmt.Fprintf(stdout, "imported %d %s\n", len(items), plural(len(items), "item", "items"))
+	return 0
+}
+
+// readTodoItems parses the non-blank todo.txt lines read from r.
+func readTodoItems(r io.Reader) ([]todo.Item, error) {
+	var items []todo.Item
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var item todo.Item
+		if err := item.UnmarshalText([]byte(line)); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		items = append(items, item)
+	}
+	return items, scanner.Err()
+}
+
+// readJSONItems parses either a JSON array of items or a stream of item
+// objects read from r.
+func readJSONItems(r io.Reader) ([]todo.Item, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var items []todo.Item
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, err
+		}
+		return items, nil
+	}
+
+	var items []todo.Item
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for n := 1; ; n++ {
+		var item todo.Item
+		err := dec.Decode(&item)
+		if errors.Is(err, io.EOF) {
+			return items, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", n, err)
+		}
+		items = append(items, item)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dawsonalex/todo"
+)
+
+func TestRun_OutputJSON(t *testing.T) {
+	path := writeRawFile(t, "(B) 2026-05-01 second @work\n\n(A) 2026-05-02 first @work\nother @home\n")
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"-f", path, "-q", "@work", "-s", "priority", "-o", "json"}, nil, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	var items []todo.Item
+	if err := json.Unmarshal(stdout.Bytes(), &items); err != nil {
+		t.Fatalf("output is not a JSON array: %v\n%s", err, stdout.String())
+	}
+	if len(items) != 2 || items[0].ID != 3 || items[0].Message != "first @work" || items[1].ID != 1 {
+		t.Errorf("got %+v, want items 3 then 1", items)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"-f", path, "-o", "jsonl", "-q", "@home"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	want := `{"id":4,"description":"other @home","done":false,"priority":"","created-date":"","completed-date":"",` +
+		`"projects":[],"contexts":["home"],"special-keys":{}}`
+	if got := strings.TrimSpace(stdout.String()); got != want {
+		t.Errorf("jsonl = %s, want %s", got, want)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"-f", path, "-o", "json", "-q", "@nowhere"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	if got := strings.TrimSpace(stdout.String()); got != "[]" {
+		t.Errorf("no matches = %s, want []", got)
+	}
+
+	if code := run([]string{"-f", path, "-o", "yaml"}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("unknown format exited %d, want 1", code)
+	}
+}
+
+func TestRun_Import(t *testing.T) {
+	pinNow(t, time.Date(2026, 5, 23, 9, 0, 0, 0, time.Local))
+
+	tests := []struct {
+		name  string
+		args  []string
+		input string
+		want  []string
+	}{
+		{
+			name:  "json array",
+			args:  []string{"-format", "json"},
+			input: `[{"id":9,"description":"buy milk @shop","priority":"A","created-date":"2026-05-01"},{"description":"call mum due:fri"}]`,
+			want:  []string{"first", "(A) 2026-05-01 buy milk @shop", "2026-05-23 call mum due:2026-05-29"},
+		},
+		{
+			name:  "json lines",
+			args:  []string{"-format", "json"},
+			input: "{\"description\":\"one\"}\n{\"description\":\"two\",\"done\":true,\"completed-date\":\"2026-05-20\"}\n",
+			want:  []string{"first", "2026-05-23 one", "x 2026-05-20 two"},
+		},
+		{
+			name:  "todo lines",
+			input: "(B) one\n\ntwo +proj\n",
+			want:  []string{"first", "(B) 2026-05-23 one", "2026-05-23 two +proj"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeRawFile(t, "first\n")
+			var stdout, stderr bytes.Buffer
+
+			args := append([]string{"import", "-f", path}, tc.args...)
+			if code := run(args, strings.NewReader(tc.input), &stdout, &stderr); code != 0 {
+				t.Fatalf("run exited %d: %s", code, stderr.String())
+			}
+
+			var got []string
+			for _, item := range readItemsFromFile(t, path) {
+				text, _ := item.MarshalText()
+				got = append(got, string(text))
+			}
+			if !sliceEqual(got, tc.want) {
+				t.Errorf("file lines = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRun_ImportFile(t *testing.T) {
+	path := writeRawFile(t, "first\n")
+	input := filepath.Join(t.TempDir(), "items.json")
+	if err := os.WriteFile(input, []byte(`[{"description":"from a file"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"import", "-f", path, "-format", "json", input}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	if got := strings.TrimSpace(stdout.String()); got != "imported 1 item" {
+		t.Errorf("stdout = %q", got)
+	}
+	if items := readItemsFromFile(t, path); len(items) != 2 || items[1].Message != "from a file" {
+		t.Errorf("items = %+v", items)
+	}
+}
+
+func TestRun_ImportDoneItems(t *testing.T) {
+	for _, tc := range []struct{ format, input string }{
+		{"todo", "x 2026-01-03 finished thing\n"},
+		{"json", `{"description":"finished thing","done":true,"completed-date":"2026-01-03"}`},
+	} {
+		path := emptyFilePath(t)
+		var stdout, stderr bytes.Buffer
+
+		if code := run([]string{"import", "-f", path, "-format", tc.format}, strings.NewReader(tc.input), &stdout, &stderr); code != 0 {
+			t.Fatalf("%s: run exited %d: %s", tc.format, code, stderr.String())
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "x 2026-01-03 finished thing\n"; string(data) != want {
+			t.Errorf("%s: file = %q, want %q with no creation date added", tc.format, data, want)
+		}
+	}
+}
+
+func TestRun_ImportInvalid(t *testing.T) {
+	const content = "first\n"
+	path := writeRawFile(t, content)
+	var stdout, stderr bytes.Buffer
+
+	input := `[{"description":"fine"},{"description":"bad","priority":"7"}]`
+	if code := run([]string{"import", "-f", path, "-format", "json"}, strings.NewReader(input), &stdout, &stderr); code != 1 {
+		t.Errorf("run exited %d, want 1", code)
+	}
+	if data, _ := os.ReadFile(path); string(data) != content {
+		t.Errorf("file changed to %q", data)
+	}
+
+	if code := run([]string{"import", "-f", path}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("import without input exited %d, want 1", code)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -9,17 +10,45 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
-	"sort"
+	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/dawsonalex/todo"
 )
 
+// now returns the current time. Tests replace it to pin dates.
+var now = time.Now
+
 // version is the build version, overridden at release time via
 // -ldflags "-X main.version=...". Defaults to "dev" for local builds.
 var version = "dev"
 
+// usage is the top-level help text, printed before the flag defaults.
+const usage = `Usage:
+  todo [flags] [item...]
+  todo <subcommand> [flags] [args...]
+  todo -version
+
+Subcommands:
+  completion <shell>   print the tab-completion script for bash, fish, or zsh
+  do <n>...            mark the numbered items as done
+  rm <n>...            delete the numbered items (alias: del)
+  edit <n> [text...]   replace an item's text, or open it in $EDITOR
+  archive              move completed items to done.txt
+  append <n> <text>    add text to the end of an item
+  prepend <n> <text>   add text to the start of an item
+  pri <n>... <A-Z>     set the priority of items (or -q to match a filter)
+  depri <n>...         remove the priority of items (or -q to match a filter)
+  undo                 restore the file as it was before the last change (-redo reverses it)
+  import [file]        add todo.txt lines or JSON items from a file or stdin
+  view <name>          list items with a view saved in the config file
+  report               count open and completed items by project, context and priority
+
+Flags:
+`
+
 // queryFlag is a repeatable -q flag value.
 type queryFlag []string
 
@@ -44,21 +73,79 @@ func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
 		return 0
 	}
 
+	// Check the config file's sections here, as subcommands only read their
+	// own.
+	cfg, err := loadConfig()
+	if err == nil {
+		err = cfg.checkSections()
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: reading config: %v\n", err)
+		return 1
+	}
+
+	if len(args) > 0 {
+		if cmd, ok := commands[args[0]]; ok {
+			return cmd(args[1:], stdin, stdout, stderr)
+		}
+	}
+	// -f picks the todo file for a subcommand after it too, as in
+	// "todo -f work.txt do 3".
+	if fileArgs, rest := cutFileFlag(args); len(fileArgs) > 0 && len(rest) > 0 {
+		if cmd, ok := commands[rest[0]]; ok {
+			return cmd(append(fileArgs, rest[1:]...), stdin, stdout, stderr)
+		}
+	}
+	return runList(args, stdin, stdout, stderr, "")
+}
+
+// cutFileFlag splits a leading -f flag and its value from args. fileArgs is
+// empty if args does not start with one.
+func cutFileFlag(args []string) (fileArgs, rest []string) {
+	if len(args) == 0 {
+		return nil, args
+	}
+	name, _, hasValue := strings.Cut(args[0], "=")
+	if name != "-f" && name != "--f" {
+		return nil, args
+	}
+	if hasValue {
+		return args[:1], args[1:]
+	}
+	if len(args) < 2 {
+		return nil, args
+	}
+	return args[:2:2], args[2:]
+}
+
+// runList handles "todo [flags] [item...]": adding the item given by the
+// arguments or the lines piped to stdin, or else listing items. view names
+// a view from the config file whose settings the listing uses for flags not
+// given in args; a view only lists.
+func runList(args []string, stdin io.Reader, stdout, stderr io.Writer, view string) int {
 	fs := flag.NewFlagSet("todo", flag.ContinueOnError)
 	fs.SetOutput(stderr)
 	fs.Usage = func() {
-		_, _ = fmt.Fprintf(stderr, "Usage:\n  todo [flags] [item...]\n  todo completion <shell>\n  todo -version\n\nSubcommands:\n  completion <shell>   print the tab-completion script for bash, fish, or zsh\n\nFlags:\n")
+		_, _ = fmt.Fprint(stderr, usage)
 		fs.PrintDefaults()
 	}
 
-	var queries queryFlag
+	var queries, excludes queryFlag
 	showVersion := fs.Bool("version", false, "print the version and exit")
-	sortField := fs.String("s", "created", "sort field: priority, created, completed")
+	sortField := fs.String("s", "created", "sort by these comma-separated fields, each descending if it starts with -: priority, created, completed, due, threshold, project, context, text, id or key:<name>")
 	filePath := fs.String("f", "", "path to todo.txt file (overrides TODO_FILE env var)")
 	showDone := fs.Bool("done", false, "include completed items in output")
+	showFuture := fs.Bool("future", false, "include items whose t: threshold date is after today")
 	verbose := fs.Bool("v", false, "print the resolved todo.txt path")
+	raw := fs.Bool("raw", false, "print bare todo.txt lines without item numbers")
+	output := fs.String("o", "text", "output format: text, json (an array) or jsonl (one object per line)")
+	colorMode := fs.String("color", "auto", "colour the listing: always, never, or auto (only on a terminal, and not if NO_COLOR is set)")
+	format := fs.String("format", "", "print each item with this Go text/template, e.g. '{{.ID}} {{.Priority}} {{.Message}}'")
+	groupBy := fs.String("group", "", "list items under a heading for each project, context, priority or due date")
 	completeWord := fs.String("complete", "", "output tab completions for word (used by shell completion scripts)")
-	fs.Var(&queries, "q", "filter term, repeatable with AND logic (e.g. -q @work -q +project)")
+	fs.Var(&queries, "q", "filter query, repeatable with AND logic (e.g. -q '@work and not +home' -q 'pri<=B')")
+	fs.Var(&excludes, "x", "exclude items matching this query, repeatable (e.g. -x @waiting)")
+	ignoreCase := fs.Bool("i", false, "ignore case when matching text in queries")
 
 	if err := fs.Parse(args); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
@@ -67,6 +154,27 @@ func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
 		return 1
 	}
 
+	cfg, err := loadConfig()
+	if err == nil && view != "" {
+		settings, ok := cfg.sections["view."+view]
+		if !ok {
+			err = fmt.Errorf("no view %q in %s", view, cfg.path)
+		} else {
+			err = cfg.apply(fs, "view."+view, settings)
+		}
+	}
+	if err == nil {
+		err = cfg.apply(fs, "", cfg.sections[""])
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: reading config: %v\n", err)
+		return 1
+	}
+	if view != "" && fs.NArg() > 0 {
+		_, _ = fmt.Fprintln(stderr, "todo: a view lists items; give flags, not text, after its name")
+		return 1
+	}
+
 	if *showVersion {
 		_, _ = fmt.Fprintf(stdout, "todo %s\n", version)
 		return 0
@@ -92,44 +200,115 @@ func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
 		_, _ = fmt.Fprintf(stdout, "todo file: %s\n", path)
 	}
 
+	adding := stdin != nil || len(fs.Args()) > 0
+	if adding {
+		unlock, ok := lockFile(path, stderr)
+		if !ok {
+			return 1
+		}
+		defer unlock()
+	}
+
 	list, err := todo.ReadFile(path)
 	if err != nil {
 		_, _ = fmt.Fprintf(stderr, "todo: reading %s: %v\n", path, err)
 		return 1
 	}
 
-	adding := false
-
 	if stdin != nil {
 		if err := addFromReader(list, stdin); err != nil {
 			_, _ = fmt.Fprintf(stderr, "todo: reading stdin: %v\n", err)
 			return 1
 		}
-		adding = true
 	}
 
 	if posArgs := fs.Args(); len(posArgs) > 0 {
+		// A subcommand name after flags is almost certainly a misplaced
+		// subcommand; "--" still allows adding an item starting with one.
+		_, isCommand := commands[posArgs[0]]
+		if start := len(args) - len(posArgs); (isCommand || posArgs[0] == "completion") && start > 0 && args[start-1] != "--" {
+			_, _ = fmt.Fprintf(stderr, "todo: %q is a subcommand: give it before flags other than -f, or add \"--\" before item text starting with it\n", posArgs[0])
+			return 1
+		}
 		text := strings.Join(posArgs, " ")
 		if err := addItem(list, text); err != nil {
 			_, _ = fmt.Fprintf(stderr, "todo: parsing item %q: %v\n", text, err)
 			return 1
 		}
-		adding = true
 	}
 
 	if adding {
-		if err := todo.WriteFile(path, list); err != nil {
-			_, _ = fmt.Fprintf(stderr, "todo: writing %s: %v\n", path, err)
+		if !saveList(path, list, stderr) {
 			return 1
 		}
 		return 0
 	}
 
 	// List mode: filter, sort, print.
+	color, err := useColor(*colorMode, stdout)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: %v\n", err)
+		return 1
+	}
+	sortKeys, err := parseSort(*sortField)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: %v\n", err)
+		return 1
+	}
+	query, err := parseQueries(queries, excludes, *ignoreCase)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: %v\n", err)
+		return 1
+	}
 	items := list.GetAll()
-	items = filterItems(items, queries, *showDone)
-	items = sortItems(items, *sortField)
-	printItems(items, stdout)
+	if !*showFuture {
+		items = hideFuture(items)
+	}
+	items = filterItems(items, query, *showDone)
+	items = sortItems(items, sortKeys)
+	switch *output {
+	case "text":
+		var tmpl *template.Template
+		if *format != "" {
+			if tmpl, err = parseFormat(*format); err != nil {
+				_, _ = fmt.Fprintf(stderr, "todo: parsing -format: %v\n", err)
+				return 1
+			}
+		}
+		switch {
+		case *groupBy != "":
+			var groups []group
+			if groups, err = groupItems(items, *groupBy); err != nil {
+				_, _ = fmt.Fprintf(stderr, "todo: %v\n", err)
+				return 1
+			}
+			err = printGroups(groups, stdout, tmpl, !*raw, color)
+		case tmpl != nil:
+			err = printTemplate(items, tmpl, stdout)
+		default:
+			printItems(items, stdout, !*raw, color)
+		}
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "todo: writing output: %v\n", err)
+			return 1
+		}
+	case "json", "jsonl":
+		if *format != "" {
+			_, _ = fmt.Fprintln(stderr, "todo: -format cannot be used with -o json or jsonl")
+			return 1
+		}
+		if *groupBy != "" {
+			_, _ = fmt.Fprintln(stderr, "todo: -group cannot be used with -o json or jsonl")
+			return 1
+		}
+		if err := printJSON(items, stdout, *output == "jsonl"); err != nil {
+			_, _ = fmt.Fprintf(stderr, "todo: writing output: %v\n", err)
+			return 1
+		}
+	default:
+		_, _ = fmt.Fprintf(stderr, "todo: unknown output format %q: want text, json or jsonl\n", *output)
+		return 1
+	}
 	return 0
 }
 
@@ -159,6 +338,12 @@ func resolvePath(pwd, flagVal string) (string, error) {
 	return filepath.Join(u.HomeDir, "todo.txt"), nil
 }
 
+// today returns midnight at the start of the current local day.
+func today() time.Time {
+	y, m, d := now().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
 // isStdinPiped reports whether stdin is a pipe rather than a terminal.
 func isStdinPiped() bool {
 	stat, err := os.Stdin.Stat()
@@ -184,79 +369,147 @@ func addFromReader(list *todo.List, r io.Reader) error {
 }
 
 // addItem parses a todo.txt line and appends it to the list.
-// If no creation date is present in the text, today's date is set.
+// If an open item has no creation date, today's date is set, and dates such
+// as due:fri are written out in full. A done item's only date is its
+// completion date, so it is not given a creation date after it.
 func addItem(list *todo.List, text string) error {
 	var item todo.Item
 	if err := item.UnmarshalText([]byte(text)); err != nil {
 		return err
 	}
-	if item.CreatedDate.IsZero() {
-		item.CreatedDate = time.Now().Truncate(24 * time.Hour)
+	if !item.Done && item.CreatedDate.IsZero() {
+		item.CreatedDate = today()
 	}
+	item.ResolveDates(now())
 	list.Add(item)
 	return nil
 }
 
-// filterItems returns items matching all query terms and respecting the showDone flag.
-// Matching is a case-sensitive substring check against the todo.txt representation of each item.
-func filterItems(items []todo.Item, queries []string, showDone bool) []todo.Item {
+// parseQueries parses each -q value as a query and combines them with AND,
+// excluding items matching any -x query. It returns nil if there are none.
+// With ignoreCase, text and regexp terms ignore case.
+func parseQueries(queries, excludes []string, ignoreCase bool) (todo.Query, error) {
+	var query todo.Query
+	add := func(q todo.Query) {
+		if ignoreCase {
+			q = todo.IgnoreCase(q)
+		}
+		if query == nil {
+			query = q
+		} else {
+			query = todo.And{Left: query, Right: q}
+		}
+	}
+	for _, s := range queries {
+		q, err := todo.ParseQuery(s, now())
+		if err != nil {
+			return nil, err
+		}
+		add(q)
+	}
+	for _, s := range excludes {
+		q, err := todo.ParseQuery(s, now())
+		if err != nil {
+			return nil, err
+		}
+		add(todo.Not{Query: q})
+	}
+	return query, nil
+}
+
+// filterItems returns items matching query, which may be nil to match all,
+// and respecting the showDone flag.
+func filterItems(items []todo.Item, query todo.Query, showDone bool) []todo.Item {
 	out := items[:0:0]
 	for _, item := range items {
 		if item.Done && !showDone {
 			continue
 		}
-		text, _ := item.MarshalText()
-		line := string(text)
-		match := true
-		for _, q := range queries {
-			if !strings.Contains(line, q) {
-				match = false
-				break
-			}
-		}
-		if match {
+		if query == nil || query.Match(item) {
 			out = append(out, item)
 		}
 	}
 	return out
 }
 
-// sortItems sorts items by the named field. Unknown fields leave order unchanged.
-func sortItems(items []todo.Item, field string) []todo.Item {
-	switch field {
-	case "priority":
-		sort.SliceStable(items, func(i, j int) bool {
-			pi, pj := items[i].Priority, items[j].Priority
-			if pi.Valid() && pj.Valid() {
-				return pi < pj
-			}
-			return pi.Valid() // valid priorities sort before invalid (no priority)
-		})
-	case "completed":
-		sort.SliceStable(items, func(i, j int) bool {
-			ti, tj := items[i].CompletedDate, items[j].CompletedDate
-			if ti.IsZero() != tj.IsZero() {
-				return !ti.IsZero() // items with a completed date sort before those without
-			}
-			return ti.Before(tj)
-		})
-	default: // "created"
-		sort.SliceStable(items, func(i, j int) bool {
-			ti, tj := items[i].CreatedDate, items[j].CreatedDate
-			if ti.IsZero() != tj.IsZero() {
-				return !ti.IsZero()
-			}
-			return ti.Before(tj)
-		})
+// hideFuture returns items without those whose t: threshold date is after
+// today.
+func hideFuture(items []todo.Item) []todo.Item {
+	out := items[:0:0]
+	for _, item := range items {
+		if t, ok := item.Threshold(); ok && t.After(today()) {
+			continue
+		}
+		out = append(out, item)
 	}
-	return items
+	return out
 }
 
-func printItems(items []todo.Item, w io.Writer) {
+// printItems writes items to w one todo.txt line each. If numbered is set,
+// each line is prefixed with the item number, zero-padded to the width of the
+// largest number printed, and overdue items are marked. If color is set,
+// lines are coloured with ANSI escape sequences.
+func printItems(items []todo.Item, w io.Writer, numbered, color bool) {
+	width := 0
+	if numbered {
+		width = idWidth(items)
+	}
 	bw := bufio.NewWriter(w)
+	writeItems(bw, items, width, numbered, color)
+	_ = bw.Flush()
+}
+
+// idWidth returns the number of digits in the largest item number in items.
+func idWidth(items []todo.Item) int {
+	width := 0
 	for _, item := range items {
-		text, _ := item.MarshalText()
-		_, _ = fmt.Fprintf(bw, "%s\n", text)
+		width = max(width, len(strconv.Itoa(int(item.ID))))
 	}
-	_ = bw.Flush()
+	return width
+}
+
+// writeItems writes items to w as printItems does, with item numbers
+// zero-padded to width.
+func writeItems(w io.Writer, items []todo.Item, width int, numbered, color bool) {
+	for _, item := range items {
+		b, _ := item.MarshalText()
+		text := string(b)
+		if color {
+			text = colorLine(item, text)
+		}
+		if numbered {
+			_, _ = fmt.Fprintf(w, "%0*d %s", width, item.ID, text)
+			if item.Overdue(now()) {
+				marker := "(overdue)"
+				if color {
+					marker = ansiOverdue + marker + ansiReset
+				}
+				_, _ = fmt.Fprint(w, " "+marker)
+			}
+			_, _ = fmt.Fprintln(w)
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "%s\n", text)
+	}
+}
+
+// printJSON writes items to w as a JSON array, or with lines as one JSON
+// object per line.
+func printJSON(items []todo.Item, w io.Writer, lines bool) error {
+	if lines {
+		enc := json.NewEncoder(w)
+		for _, item := range items {
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if items == nil {
+		items = []todo.Item{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(items)
 }
@@ -2,14 +2,31 @@ package main
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/dawsonalex/todo"
 )
 
+// TestMain keeps the backups every write takes out of the user's real state
+// directory, and keeps the user's config file from changing test results.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "todo-backups-*")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	_ = os.Setenv("TODO_BACKUP_DIR", dir)
+	_ = os.Setenv("TODO_CONFIG", filepath.Join(dir, "no-config.toml"))
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 // writeRawFile writes a raw todo.txt string to a temp file and returns the path.
 func writeRawFile(t *testing.T, content string) string {
 	t.Helper()
@@ -86,6 +103,63 @@ func TestRun_AddSingleWordArg(t *testing.T) {
 	}
 }
 
+func TestRun_SubcommandAfterFileFlag(t *testing.T) {
+	for _, fileArgs := range [][]string{{"-f", ""}, {"--f", ""}, {"-f="}} {
+		path := writeRawFile(t, "first\nsecond\n")
+		fileArgs[len(fileArgs)-1] += path
+		var stdout, stderr bytes.Buffer
+
+		args := append(fileArgs, "do", "2")
+		if code := run(args, nil, &stdout, &stderr); code != 0 {
+			t.Fatalf("%q exited %d: %s", args, code, stderr.String())
+		}
+		items := readItemsFromFile(t, path)
+		if len(items) != 2 || items[0].Done || !items[1].Done {
+			t.Errorf("%q: items = %+v, want second done", args, items)
+		}
+	}
+}
+
+func TestRun_SubcommandAfterFlags(t *testing.T) {
+	path := writeRawFile(t, "first\n")
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"-f", path, "-v", "do", "1"}, nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("run exited %d, want 1", code)
+	}
+	if !strings.Contains(stderr.String(), `"do" is a subcommand`) {
+		t.Errorf("stderr = %q", stderr.String())
+	}
+	if items := readItemsFromFile(t, path); len(items) != 1 {
+		t.Errorf("items = %+v, want the file unchanged", items)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := run([]string{"-f", path, "--", "do", "laundry"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	if items := readItemsFromFile(t, path); len(items) != 2 || items[1].Message != "do laundry" {
+		t.Errorf("items = %+v, want do laundry added", items)
+	}
+}
+
+func TestRun_AddDoneItem(t *testing.T) {
+	path := emptyFilePath(t)
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"-f", path, "x 2026-01-02 finished thing"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "x 2026-01-02 finished thing\n"; string(data) != want {
+		t.Errorf("file = %q, want %q with no creation date added", data, want)
+	}
+}
+
 func TestRun_AddFromStdin(t *testing.T) {
 	path := emptyFilePath(t)
 	stdin := strings.NewReader("buy milk\ncall dentist\n")
@@ -108,6 +182,25 @@ func TestRun_AddFromStdin(t *testing.T) {
 	}
 }
 
+func TestRun_AddRelativeDates(t *testing.T) {
+	pinNow(t, time.Date(2026, 5, 23, 9, 0, 0, 0, time.Local))
+	path := emptyFilePath(t)
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"-f", path, "call", "the", "bank", "due:fri", "t:+3d"}, nil, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+
+	items := readItemsFromFile(t, path)
+	if len(items) != 1 {
+		t.Fatalf("want 1 item, got %d", len(items))
+	}
+	if want := "call the bank due:2026-05-29 t:2026-05-26"; items[0].Message != want {
+		t.Errorf("message = %q, want %q", items[0].Message, want)
+	}
+}
+
 func TestRun_QueryFilter(t *testing.T) {
 	path := writeRawFile(t, "fix bug @work\nbuy milk @home\nwrite tests @work\n")
 	var stdout, stderr bytes.Buffer
@@ -146,8 +239,81 @@ func TestRun_QueryFilterAND(t *testing.T) {
 	}
 }
 
+func TestRun_QueryLanguage(t *testing.T) {
+	pinNow(t, time.Date(2026, 5, 23, 9, 0, 0, 0, time.Local))
+	path := writeRawFile(t, "(A) fix bug @work due:2026-05-20\n(C) buy milk @home\nplan @workshop +home due:2026-06-01\n")
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"-f", path, "-raw", "-q", "@work or @workshop", "-q", "not +home or due<today"}, nil, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+
+	want := []string{"(A) fix bug @work due:2026-05-20"}
+	if got := outputLines(stdout.String()); !sliceEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRun_QueryExclude(t *testing.T) {
+	const content = "call Bob @phone\nchase invoice @waiting\nbuy milk @home\n"
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "negated term",
+			args: []string{"-q", "-@waiting"},
+			want: []string{"call Bob @phone", "buy milk @home"},
+		},
+		{
+			name: "exclude flag",
+			args: []string{"-x", "@waiting", "-x", "@home"},
+			want: []string{"call Bob @phone"},
+		},
+		{
+			name: "case-sensitive text",
+			args: []string{"-q", "bob"},
+			want: nil,
+		},
+		{
+			name: "ignore case",
+			args: []string{"-i", "-q", "bob"},
+			want: []string{"call Bob @phone"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeRawFile(t, content)
+			var stdout, stderr bytes.Buffer
+
+			args := append([]string{"-f", path, "-raw"}, tc.args...)
+			if code := run(args, nil, &stdout, &stderr); code != 0 {
+				t.Fatalf("run exited %d: %s", code, stderr.String())
+			}
+			if got := outputLines(stdout.String()); !sliceEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRun_QueryInvalid(t *testing.T) {
+	path := writeRawFile(t, "fix bug @work\n")
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"-f", path, "-q", "pri<=7"}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("run exited %d, want 1", code)
+	}
+	if !strings.Contains(stderr.String(), "invalid query") {
+		t.Errorf("stderr = %q, want an invalid query error", stderr.String())
+	}
+}
+
 func TestRun_ShowDoneFlag(t *testing.T) {
-	// "x 2024-01-01 done task" → Done=true, CreatedDate=2024-01-01, Message="done task"
+	// "x 2024-01-01 done task" → Done=true, CompletedDate=2024-01-01, Message="done task"
 	path := writeRawFile(t, "x 2024-01-01 done task\nactive task\n")
 
 	t.Run("done items excluded by default", func(t *testing.T) {
@@ -242,6 +408,55 @@ func TestRun_SortByCompleted(t *testing.T) {
 	}
 }
 
+func TestRun_SortByDue(t *testing.T) {
+	path := writeRawFile(t, "no due\nlater due:2026-06-01\nsooner due:2026-05-01\n")
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"-f", path, "-raw", "-s", "due"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	want := []string{"sooner due:2026-05-01", "later due:2026-06-01", "no due"}
+	if got := outputLines(stdout.String()); !sliceEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRun_Threshold(t *testing.T) {
+	pinNow(t, time.Date(2026, 5, 23, 9, 0, 0, 0, time.Local))
+	path := writeRawFile(t, "now t:2026-05-23\nlater t:2026-05-24\nalways\n")
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"-f", path, "-raw"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	want := []string{"now t:2026-05-23", "always"}
+	if got := outputLines(stdout.String()); !sliceEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"-f", path, "-raw", "-future"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	if got := outputLines(stdout.String()); len(got) != 3 {
+		t.Errorf("with -future got %q, want all 3 items", got)
+	}
+}
+
+func TestRun_Overdue(t *testing.T) {
+	pinNow(t, time.Date(2026, 5, 23, 9, 0, 0, 0, time.Local))
+	path := writeRawFile(t, "late due:2026-05-22\nin time due:2026-05-23\n")
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"-f", path}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	want := []string{"1 late due:2026-05-22 (overdue)", "2 in time due:2026-05-23"}
+	if got := outputLines(stdout.String()); !sliceEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
 func TestRun_VerboseFlag(t *testing.T) {
 	path := emptyFilePath(t)
 	var stdout, stderr bytes.Buffer
@@ -254,3 +469,35 @@ func TestRun_VerboseFlag(t *testing.T) {
 		t.Errorf("stdout %q should contain resolved path %q", stdout.String(), path)
 	}
 }
+
+func TestRun_NumberedListing(t *testing.T) {
+	var content strings.Builder
+	for i := 1; i <= 10; i++ {
+		fmt.Fprintf(&content, "(%c) task %d\n", 'A'+rune(10-i), i)
+	}
+	path := writeRawFile(t, content.String())
+
+	t.Run("numbers follow file lines after sorting and filtering", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"-f", path, "-s", "priority", "-q", "task 1"}, nil, &stdout, &stderr)
+		if code != 0 {
+			t.Fatalf("run exited %d: %s", code, stderr.String())
+		}
+		want := []string{"10 (A) task 10", "01 (J) task 1"}
+		if got := outputLines(stdout.String()); !sliceEqual(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("raw omits numbers", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"-f", path, "-raw", "-q", "task 1"}, nil, &stdout, &stderr)
+		if code != 0 {
+			t.Fatalf("run exited %d: %s", code, stderr.String())
+		}
+		want := []string{"(J) task 1", "(A) task 10"}
+		if got := outputLines(stdout.String()); !sliceEqual(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+}
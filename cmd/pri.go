@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/dawsonalex/todo"
+)
+
+// runPri handles "todo pri <n>... <A-Z>" and "todo pri -q <term> <A-Z>",
+// setting the priority of the numbered items or of every open item matching
+// the filter.
+func runPri(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	fs, filePath := newFlagSet("pri", "pri [flags] <n>... <A-Z>\n  todo pri [flags] -q <term> <A-Z>", stderr)
+	var queries queryFlag
+	fs.Var(&queries, "q", "set the priority of every open item matching this filter term, repeatable")
+	if err := parseFlags(fs, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+
+	posArgs := fs.Args()
+	if len(posArgs) == 0 {
+		fs.Usage()
+		return 1
+	}
+	priority, err := todo.ParsePriority(posArgs[len(posArgs)-1])
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: %v\n", err)
+		return 1
+	}
+
+	return setPriority(*filePath, posArgs[:len(posArgs)-1], queries, priority, stdout, stderr)
+}
+
+// runDepri handles "todo depri <n>..." and "todo depri -q <term>", removing
+// the priority from the numbered items or from every open item matching the
+// filter.
+func runDepri(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	fs, filePath := newFlagSet("depri", "depri [flags] <n>...\n  todo depri [flags] -q <term>", stderr)
+	var queries queryFlag
+	fs.Var(&queries, "q", "remove the priority of every open item matching this filter term, repeatable")
+	if err := parseFlags(fs, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+
+	return setPriority(*filePath, fs.Args(), queries, 0, stdout, stderr)
+}
+
+// setPriority sets priority on the items selected by either numbers or
+// queries, then writes the file and prints the changed items. A zero
+// priority removes it.
+func setPriority(filePath string, numbers, queries []string, priority todo.Priority, stdout, stderr io.Writer) int {
+	path, list, unlock, ok := loadList(filePath, stderr)
+	if !ok {
+		return 1
+	}
+	defer unlock()
+
+	var ids []todo.Id
+	switch {
+	case len(queries) > 0 && len(numbers) > 0:
+		_, _ = fmt.Fprintln(stderr, "todo: give item numbers or -q filters, not both")
+		return 1
+	case len(queries) > 0:
+		query, err := parseQueries(queries, nil, false)
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "todo: %v\n", err)
+			return 1
+		}
+		for _, item := range filterItems(list.GetAll(), query, false) {
+			ids = append(ids, item.ID)
+		}
+		if len(ids) == 0 {
+			_, _ = fmt.Fprintln(stderr, "todo: no items match")
+			return 0
+		}
+	default:
+		var err error
+		if ids, err = parseIds(list, numbers); err != nil {
+			_, _ = fmt.Fprintf(stderr, "todo: %v\n", err)
+			return 1
+		}
+	}
+
+	changed := make([]todo.Item, 0, len(ids))
+	for _, id := range ids {
+		item, _ := list.Get(id)
+		item.Priority = priority
+		if err := list.Set(id, item); err != nil {
+			_, _ = fmt.Fprintf(stderr, "todo: %v\n", err)
+			return 1
+		}
+		changed = append(changed, item)
+	}
+
+	if !saveList(path, list, stderr) {
+		return 1
+	}
+
+	printItems(changed, stdout, true, false)
+	return 0
+}
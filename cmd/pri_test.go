@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_Pri(t *testing.T) {
+	const content = "(C) first +old\nsecond +old\nthird +new\nx done +old\n"
+
+	tests := []struct {
+		name      string
+		args      []string
+		wantLines []string
+	}{
+		{
+			name:      "numbered items",
+			args:      []string{"pri", "2", "3", "b"},
+			wantLines: []string{"(C) first +old", "(B) second +old", "(B) third +new", "x done +old"},
+		},
+		{
+			name:      "bulk by filter skips done items",
+			args:      []string{"pri", "-q", "+old", "A"},
+			wantLines: []string{"(A) first +old", "(A) second +old", "third +new", "x done +old"},
+		},
+		{
+			name:      "depri numbered",
+			args:      []string{"depri", "1"},
+			wantLines: []string{"first +old", "second +old", "third +new", "x done +old"},
+		},
+		{
+			name:      "depri by filter",
+			args:      []string{"depri", "-q", "+old"},
+			wantLines: []string{"first +old", "second +old", "third +new", "x done +old"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeRawFile(t, content)
+			var stdout, stderr bytes.Buffer
+
+			args := append([]string{tc.args[0], "-f", path}, tc.args[1:]...)
+			if code := run(args, nil, &stdout, &stderr); code != 0 {
+				t.Fatalf("run exited %d: %s", code, stderr.String())
+			}
+
+			items := readItemsFromFile(t, path)
+			var got []string
+			for _, item := range items {
+				text, _ := item.MarshalText()
+				got = append(got, string(text))
+			}
+			if !sliceEqual(got, tc.wantLines) {
+				t.Errorf("file lines = %q, want %q", got, tc.wantLines)
+			}
+		})
+	}
+}
+
+func TestRun_PriInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"bad letter", []string{"pri", "1", "AA"}, "invalid priority"},
+		{"digit", []string{"pri", "1", "2"}, "invalid priority"},
+		{"numbers and filter", []string{"pri", "-q", "+old", "1", "A"}, "not both"},
+		{"out of range", []string{"depri", "9"}, "no item 9"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeRawFile(t, "(C) first +old\n")
+			var stdout, stderr bytes.Buffer
+
+			args := append([]string{tc.args[0], "-f", path}, tc.args[1:]...)
+			if code := run(args, nil, &stdout, &stderr); code != 1 {
+				t.Fatalf("run exited %d, want 1", code)
+			}
+			if !strings.Contains(stderr.String(), tc.wantErr) {
+				t.Errorf("stderr = %q, want it to contain %q", stderr.String(), tc.wantErr)
+			}
+			if items := readItemsFromFile(t, path); items[0].Priority != 'C' {
+				t.Errorf("priority changed to %q", items[0].Priority)
+			}
+		})
+	}
+}
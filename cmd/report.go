@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dawsonalex/todo"
+)
+
+// report is the summary "todo report" prints. Ages are in whole days since
+// an item's creation date; items without one are left out of the average.
+type report struct {
+	Open       int           `json:"open"`
+	Done       int           `json:"done"`
+	AverageAge *float64      `json:"average-age-days"`
+	Projects   []reportRow   `json:"projects"`
+	Contexts   []reportRow   `json:"contexts"`
+	Priorities []reportRow   `json:"priorities"`
+	PerDay     []reportCount `json:"completed-per-day"`
+	PerWeek    []reportCount `json:"completed-per-week"`
+}
+
+// reportRow is the counts for one project, context or priority. Name is
+// empty for items with none.
+type reportRow struct {
+	heading    string
+	Name       string   `json:"name"`
+	Open       int      `json:"open"`
+	Done       int      `json:"done"`
+	AverageAge *float64 `json:"average-age-days"`
+}
+
+// reportCount is the number of items completed on a day, or in the week
+// starting on that Monday.
+type reportCount struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// runReport handles "todo report", summarising the open and completed items
+// in the todo file and done.txt by project, context and priority, and
+// counting the items completed over recent days and weeks.
+func runReport(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	fs, filePath := newFlagSet("report", "report [flags]", stderr)
+	doneFlag := fs.String("d", "", "path to done.txt file (overrides DONE_FILE env var)")
+	days := fs.Int("days", 7, "count the items completed on each of this many days, up to today")
+	weeks := fs.Int("weeks", 4, "count the items completed in each of this many weeks, up to this week")
+	output := fs.String("o", "text", "output format: text or json")
+	var queries queryFlag
+	fs.Var(&queries, "q", "only count items matching this query, repeatable")
+	if err := parseFlags(fs, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 1
+	}
+	if *days < 0 || *weeks < 0 {
+		_, _ = fmt.Fprintln(stderr, "todo: -days and -weeks must not be negative")
+		return 1
+	}
+	if *output != "text" && *output != "json" {
+		_, _ = fmt.Fprintf(stderr, "todo: unknown output format %q: want text or json\n", *output)
+		return 1
+	}
+	query, err := parseQueries(queries, nil, false)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: %v\n", err)
+		return 1
+	}
+
+	// Reading only, so without the lock that would hold up other commands.
+	pwd, err := os.Getwd()
+	if err != nil {
+		pwd = ""
+	}
+	path, err := resolvePath(pwd, *filePath)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: resolving path: %v\n", err)
+		return 1
+	}
+	list, err := todo.ReadFile(path)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: reading %s: %v\n", path, err)
+		return 1
+	}
+	donePath := resolveDonePath(path, *doneFlag)
+	doneList, err := todo.ReadFile(donePath)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: reading %s: %v\n", donePath, err)
+		return 1
+	}
+
+	items := append(list.GetAll(), doneList.GetAll()...)
+	items = filterItems(items, query, true)
+	r, err := buildReport(items, today(), *days, *weeks)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: %v\n", err)
+		return 1
+	}
+
+	if *output == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(r)
+	} else {
+		err = printReport(r, stdout)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: writing output: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// buildReport summarises items as of the day today, counting completions
+// on each of the last days days and in each of the last weeks weeks.
+func buildReport(items []todo.Item, today time.Time, days, weeks int) (*report, error) {
+	r := &report{}
+	var total tally
+	for _, item := range items {
+		total.add(item, today)
+	}
+	r.Open, r.Done, r.AverageAge = total.open, total.done, total.average()
+
+	var err error
+	if r.Projects, err = reportRows(items, "project", today); err != nil {
+		return nil, err
+	}
+	if r.Contexts, err = reportRows(items, "context", today); err != nil {
+		return nil, err
+	}
+	if r.Priorities, err = reportRows(items, "priority", today); err != nil {
+		return nil, err
+	}
+
+	perDay := make(map[string]int)
+	perWeek := make(map[string]int)
+	for _, item := range items {
+		if !item.Done || item.CompletedDate.IsZero() {
+			continue
+		}
+		perDay[item.CompletedDate.Format(dateLayout)]++
+		perWeek[weekStart(item.CompletedDate).Format(dateLayout)]++
+	}
+	r.PerDay = make([]reportCount, 0, days)
+	for i := days - 1; i >= 0; i-- {
+		day := today.AddDate(0, 0, -i).Format(dateLayout)
+		r.PerDay = append(r.PerDay, reportCount{Date: day, Count: perDay[day]})
+	}
+	r.PerWeek = make([]reportCount, 0, weeks)
+	for i := weeks - 1; i >= 0; i-- {
+		week := weekStart(today).AddDate(0, 0, -7*i).Format(dateLayout)
+		r.PerWeek = append(r.PerWeek, reportCount{Date: week, Count: perWeek[week]})
+	}
+	return r, nil
+}
+
+// reportRows counts items in the groups -group field would list them
+// under, so an item with two projects counts towards both.
+func reportRows(items []todo.Item, field string, today time.Time) ([]reportRow, error) {
+	groups, err := groupItems(items, field)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]reportRow, 0, len(groups))
+	for _, g := range groups {
+		var t tally
+		for _, item := range g.items {
+			t.add(item, today)
+		}
+		name := ""
+		switch {
+		case g.heading == noGroup:
+		case field == "priority":
+			// "(A)"
+			name = g.heading[1 : len(g.heading)-1]
+		default:
+			// "+project" or "@context"
+			name = g.heading[1:]
+		}
+		rows = append(rows, reportRow{heading: g.heading, Name: name, Open: t.open, Done: t.done, AverageAge: t.average()})
+	}
+	return rows, nil
+}
+
+// tally counts open and done items and sums the ages of the open ones.
+type tally struct {
+	open, done int
+	aged       int // open items with a creation date
+	ageDays    int
+}
+
+func (t *tally) add(item todo.Item, today time.Time) {
+	if item.Done {
+		t.done++
+		return
+	}
+	t.open++
+	if !item.CreatedDate.IsZero() {
+		t.aged++
+		t.ageDays += int(math.Round(today.Sub(item.CreatedDate).Hours() / 24))
+	}
+}
+
+// average returns the average age in days of the open items with a creation
+// date, rounded to one decimal place, or nil if there are none.
+func (t tally) average() *float64 {
+	if t.aged == 0 {
+		return nil
+	}
+	avg := math.Round(float64(t.ageDays)/float64(t.aged)*10) / 10
+	return &avg
+}
+
+// weekStart returns the Monday of the week containing day.
+func weekStart(day time.Time) time.Time {
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// printReport writes r to w as aligned tables.
+func printReport(r *report, w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "%d open, %d done\n", r.Open, r.Done)
+	if r.AverageAge != nil {
+		_, _ = fmt.Fprintf(tw, "open items are %.1f days old on average\n", *r.AverageAge)
+	}
+
+	for _, table := range []struct {
+		title string
+		rows  []reportRow
+	}{
+		{"PROJECT", r.Projects},
+		{"CONTEXT", r.Contexts},
+		{"PRIORITY", r.Priorities},
+	} {
+		if len(table.rows) == 0 {
+			continue
+		}
+		_, _ = fmt.Fprintf(tw, "\n%s\tOPEN\tDONE\tAVG AGE\n", table.title)
+		for _, row := range table.rows {
+			_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", row.heading, row.Open, row.Done, formatAge(row.AverageAge))
+		}
+	}
+
+	for _, table := range []struct {
+		title  string
+		counts []reportCount
+	}{
+		{"COMPLETED", r.PerDay},
+		{"WEEK OF", r.PerWeek},
+	} {
+		if len(table.counts) == 0 {
+			continue
+		}
+		_, _ = fmt.Fprintf(tw, "\n%s\tITEMS\n", table.title)
+		for _, c := range table.counts {
+			_, _ = fmt.Fprintf(tw, "%s\t%d\n", c.Date, c.Count)
+		}
+	}
+	return tw.Flush()
+}
+
+// formatAge returns an average age for a report table, or "-" if there is
+// none.
+func formatAge(age *float64) string {
+	if age == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f", *age)
+}
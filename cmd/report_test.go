@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// reportTodo and reportDone are a todo file and done.txt for report tests,
+// which pin today to Sunday 2026-10-18.
+const (
+	reportTodo = "(A) 2026-10-01 fix login +api @desk\n" +
+		"2026-10-10 write docs +api +web\n" +
+		"x 2026-10-16 2026-10-02 deploy +web\n" +
+		"call bob @phone\n"
+	reportDone = "x 2026-10-06 2026-09-20 old thing +api\n"
+)
+
+func writeReportFiles(t *testing.T) string {
+	t.Helper()
+	pinNow(t, time.Date(2026, 10, 18, 9, 0, 0, 0, time.Local))
+	path := writeRawFile(t, reportTodo)
+	if err := os.WriteFile(filepath.Join(filepath.Dir(path), "done.txt"), []byte(reportDone), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRun_Report(t *testing.T) {
+	path := writeReportFiles(t)
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"report", "-f", path, "-days", "3", "-weeks", "2"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	want := `3 open, 2 done
+open items are 12.5 days old on average
+
+PROJECT  OPEN  DONE  AVG AGE
++api     2     1     12.5
++web     1     1     8.0
+(none)   1     0     -
+
+CONTEXT  OPEN  DONE  AVG AGE
+@desk    1     0     17.0
+@phone   1     0     -
+(none)   1     2     8.0
+
+PRIORITY  OPEN  DONE  AVG AGE
+(A)       1     0     17.0
+(none)    2     2     8.0
+
+COMPLETED   ITEMS
+2026-10-16  1
+2026-10-17  0
+2026-10-18  0
+
+WEEK OF     ITEMS
+2026-10-05  1
+2026-10-12  1
+`
+	if stdout.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", stdout.String(), want)
+	}
+}
+
+func TestRun_ReportJSON(t *testing.T) {
+	path := writeReportFiles(t)
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"report", "-f", path, "-o", "json", "-q", "+api", "-days", "0", "-weeks", "3"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	var got report
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("decoding %s: %v", stdout.String(), err)
+	}
+	if got.Open != 2 || got.Done != 1 || got.AverageAge == nil || *got.AverageAge != 12.5 {
+		t.Errorf("totals = %d open, %d done, age %v", got.Open, got.Done, got.AverageAge)
+	}
+	var names []string
+	for _, row := range got.Projects {
+		names = append(names, row.Name)
+	}
+	if want := []string{"api", "web"}; !sliceEqual(names, want) {
+		t.Errorf("projects = %q, want %q", names, want)
+	}
+	if len(got.Priorities) != 2 || got.Priorities[0].Name != "A" || got.Priorities[1].Name != "" {
+		t.Errorf("priorities = %+v", got.Priorities)
+	}
+	if len(got.PerDay) != 0 {
+		t.Errorf("completed-per-day = %+v, want none", got.PerDay)
+	}
+	wantWeeks := []reportCount{{"2026-09-28", 0}, {"2026-10-05", 1}, {"2026-10-12", 0}}
+	if len(got.PerWeek) != len(wantWeeks) {
+		t.Fatalf("completed-per-week = %+v, want %+v", got.PerWeek, wantWeeks)
+	}
+	for i := range wantWeeks {
+		if got.PerWeek[i] != wantWeeks[i] {
+			t.Errorf("completed-per-week = %+v, want %+v", got.PerWeek, wantWeeks)
+			break
+		}
+	}
+}
+
+func TestRun_ReportWithoutDoneFile(t *testing.T) {
+	pinNow(t, time.Date(2026, 10, 18, 9, 0, 0, 0, time.Local))
+	path := writeRawFile(t, reportTodo)
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"report", "-f", path}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), "3 open, 1 done\n") {
+		t.Errorf("got %q", stdout.String())
+	}
+}
+
+func TestRun_ReportTagNames(t *testing.T) {
+	pinNow(t, time.Date(2026, 10, 18, 9, 0, 0, 0, time.Local))
+	path := writeRawFile(t, "(B) fix +(web) @home) +foo)\n")
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"report", "-f", path, "-o", "json"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	var got report
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("decoding %s: %v", stdout.String(), err)
+	}
+	var names []string
+	for _, rows := range [][]reportRow{got.Projects, got.Contexts, got.Priorities} {
+		for _, row := range rows {
+			names = append(names, row.Name)
+		}
+	}
+	if want := []string{"(web)", "foo)", "home)", "B"}; !sliceEqual(names, want) {
+		t.Errorf("names = %q, want %q", names, want)
+	}
+}
+
+// TestRun_ReportWhileLocked checks that report only reads, so it does not
+// wait for another command holding the lock on the todo file.
+func TestRun_ReportWhileLocked(t *testing.T) {
+	path := writeRawFile(t, "first\n")
+	unlock, ok := lockFile(path, io.Discard)
+	if !ok {
+		t.Fatal("locking the todo file failed")
+	}
+	defer unlock()
+	var stdout, stderr bytes.Buffer
+
+	start := time.Now()
+	if code := run([]string{"report", "-f", path}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("report took %v with the file locked", elapsed)
+	}
+}
+
+func TestRun_ReportErrors(t *testing.T) {
+	path := writeRawFile(t, "first\n")
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-o", "csv"}, "unknown output format"},
+		{[]string{"-days", "-1"}, "must not be negative"},
+		{[]string{"-q", "pri<=7"}, "todo:"},
+		{[]string{"extra"}, "Usage:"},
+	}
+	for _, tc := range tests {
+		var stdout, stderr bytes.Buffer
+		args := append([]string{"report", "-f", path}, tc.args...)
+		if code := run(args, nil, &stdout, &stderr); code != 1 {
+			t.Errorf("%q exited %d, want 1", tc.args, code)
+		}
+		if !strings.Contains(stderr.String(), tc.want) {
+			t.Errorf("%q: stderr = %q, want %q", tc.args, stderr.String(), tc.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/dawsonalex/todo"
+)
+
+// runRm handles "todo rm <n>..." (alias "del"), deleting each numbered item.
+// When stdin is a terminal the items are listed and confirmation is asked
+// for first, unless -y is given.
+func runRm(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs, filePath := newFlagSet("rm", "rm [flags] <n>...", stderr)
+	yes := fs.Bool("y", false, "delete without asking for confirmation")
+	if err := parseFlags(fs, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+
+	path, list, unlock, ok := loadList(*filePath, stderr)
+	if !ok {
+		return 1
+	}
+	defer unlock()
+
+	ids, err := parseIds(list, fs.Args())
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "todo: %v\n", err)
+		return 1
+	}
+
+	removed := make([]todo.Item, 0, len(ids))
+	for _, id := range ids {
+		item, _ := list.Get(id)
+		removed = append(removed, item)
+	}
+
+	// run receives a nil stdin when it is a terminal.
+	if stdin == nil && !*yes {
+		printItems(removed, stdout, true, false)
+		if !confirm(fmt.Sprintf("Delete %d %s?", len(removed), plural(len(removed), "item", "items")), stdout) {
+			_, _ = fmt.Fprintln(stderr, "todo: nothing deleted")
+			return 0
+		}
+	}
+
+	for _, id := range ids {
+		if err := list.Remove(id); err != nil {
+			_, _ = fmt.Fprintf(stderr, "todo: %v\n", err)
+			return 1
+		}
+	}
+
+	if !saveList(path, list, stderr) {
+		return 1
+	}
+
+	if stdin != nil || *yes {
+		printItems(removed, stdout, true, false)
+	}
+	return 0
+}
+
+// plural returns one if n is 1, and many otherwise.
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
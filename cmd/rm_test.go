@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// answerPrompt makes confirm read answer for the duration of the test.
+func answerPrompt(t *testing.T, answer string) {
+	t.Helper()
+	prev := promptInput
+	promptInput = strings.NewReader(answer)
+	t.Cleanup(func() { promptInput = prev })
+}
+
+func TestRun_Rm(t *testing.T) {
+	path := writeRawFile(t, "first\nsecond\nthird\n")
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"rm", "-f", path, "-y", "1", "2"}, nil, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+
+	want := []string{"1 first", "2 second"}
+	if got := outputLines(stdout.String()); !sliceEqual(got, want) {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+
+	items := readItemsFromFile(t, path)
+	if len(items) != 1 || items[0].Message != "third" || items[0].ID != 3 {
+		t.Errorf("remaining items = %+v, want only third as item 3", items)
+	}
+}
+
+func TestRun_RmConfirm(t *testing.T) {
+	tests := []struct {
+		answer    string
+		wantItems int
+	}{
+		{"y\n", 1},
+		{"yes\n", 1},
+		{"n\n", 2},
+		{"\n", 2},
+		{"", 2},
+	}
+	for _, tc := range tests {
+		t.Run(strings.TrimSpace(tc.answer), func(t *testing.T) {
+			answerPrompt(t, tc.answer)
+			path := writeRawFile(t, "first\nsecond\n")
+			var stdout, stderr bytes.Buffer
+
+			if code := run([]string{"rm", "-f", path, "2"}, nil, &stdout, &stderr); code != 0 {
+				t.Fatalf("run exited %d: %s", code, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), "2 second\nDelete 1 item? [y/N] ") {
+				t.Errorf("stdout = %q, want item listing and prompt", stdout.String())
+			}
+			if got := len(readItemsFromFile(t, path)); got != tc.wantItems {
+				t.Errorf("got %d items, want %d", got, tc.wantItems)
+			}
+		})
+	}
+}
+
+func TestRun_RmPipedStdinSkipsPrompt(t *testing.T) {
+	answerPrompt(t, "n\n")
+	path := writeRawFile(t, "first\nsecond\n")
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"del", "-f", path, "1"}, strings.NewReader(""), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	if strings.Contains(stdout.String(), "[y/N]") {
+		t.Errorf("stdout = %q, should not prompt", stdout.String())
+	}
+	if got := len(readItemsFromFile(t, path)); got != 1 {
+		t.Errorf("got %d items, want 1", got)
+	}
+}
+
+func TestRun_RmInvalid(t *testing.T) {
+	const content = "first\nsecond\n"
+	path := writeRawFile(t, content)
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"rm", "-f", path, "-y", "1", "3"}, nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("run exited %d, want 1", code)
+	}
+	if !strings.Contains(stderr.String(), "no item 3") {
+		t.Errorf("stderr = %q, want it to mention item 3", stderr.String())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != content {
+		t.Errorf("file changed to %q", data)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dawsonalex/todo"
+)
+
+// sortKey is one field of a -s sort.
+type sortKey struct {
+	desc bool
+	// value returns the item's value for the field, and false if it has
+	// none. Values that are both whole numbers compare as numbers, others
+	// as text.
+	value func(item *todo.Item) (string, bool)
+}
+
+// sortFields are the fields -s accepts besides key:<name>.
+var sortFields = map[string]func(item *todo.Item) (string, bool){
+	"priority": func(item *todo.Item) (string, bool) {
+		return item.Priority.String(), item.Priority.Valid()
+	},
+	"created": func(item *todo.Item) (string, bool) {
+		return formatDate(dateLayout, item.CreatedDate), !item.CreatedDate.IsZero()
+	},
+	"completed": func(item *todo.Item) (string, bool) {
+		return formatDate(dateLayout, item.CompletedDate), !item.CompletedDate.IsZero()
+	},
+	"due": func(item *todo.Item) (string, bool) {
+		date, ok := item.Due()
+		return formatDate(dateLayout, date), ok
+	},
+	"threshold": func(item *todo.Item) (string, bool) {
+		date, ok := item.Threshold()
+		return formatDate(dateLayout, date), ok
+	},
+	"project": func(item *todo.Item) (string, bool) { return firstTag(item.Projects) },
+	"context": func(item *todo.Item) (string, bool) { return firstTag(item.Contexts) },
+	"text": func(item *todo.Item) (string, bool) {
+		return strings.ToLower(item.Message), true
+	},
+	"id": func(item *todo.Item) (string, bool) {
+		return strconv.Itoa(int(item.ID)), true
+	},
+}
+
+// firstTag returns the alphabetically first of tags, ignoring case.
+func firstTag(tags []string) (string, bool) {
+	if len(tags) == 0 {
+		return "", false
+	}
+	first := strings.ToLower(tags[0])
+	for _, tag := range tags[1:] {
+		first = min(first, strings.ToLower(tag))
+	}
+	return first, true
+}
+
+// parseSort parses a -s value: comma-separated fields, each sorted in
+// descending order if it starts with "-".
+func parseSort(spec string) ([]sortKey, error) {
+	var keys []sortKey
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		name, desc := strings.CutPrefix(field, "-")
+
+		if key, ok := strings.CutPrefix(name, "key:"); ok && key != "" {
+			keys = append(keys, sortKey{desc: desc, value: func(item *todo.Item) (string, bool) {
+				value, ok := item.SpecialKeys[key]
+				return value, ok
+			}})
+			continue
+		}
+		value, ok := sortFields[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown sort field %q: want priority, created, completed, due, threshold, project, context, text, id or key:<name>", field)
+		}
+		keys = append(keys, sortKey{desc: desc, value: value})
+	}
+	return keys, nil
+}
+
+// sortItems sorts items by keys in turn. Items without a value for a field
+// sort after those with one, in either direction, and items that compare
+// equal keep their order.
+func sortItems(items []todo.Item, keys []sortKey) []todo.Item {
+	slices.SortStableFunc(items, func(a, b todo.Item) int {
+		for _, key := range keys {
+			va, oka := key.value(&a)
+			vb, okb := key.value(&b)
+			if oka != okb {
+				if oka {
+					return -1
+				}
+				return 1
+			}
+			c := compareValues(va, vb)
+			if key.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+	return items
+}
+
+// compareValues compares two sort values, as numbers if both are whole
+// numbers and otherwise as text.
+func compareValues(a, b string) int {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	if errA == nil && errB == nil {
+		return cmp.Compare(na, nb)
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_SortMultiKey(t *testing.T) {
+	const content = "(B) 2026-01-03 beta +web due:2026-06-01 est:10\n" +
+		"(A) 2026-01-02 alpha +api\n" +
+		"(B) 2026-01-01 gamma +Web +api due:2026-05-01 est:9\n" +
+		"2026-01-04 delta\n"
+
+	tests := []struct {
+		sort string
+		want []string // messages in order
+	}{
+		{"priority,due", []string{"alpha +api", "gamma +Web +api due:2026-05-01 est:9", "beta +web due:2026-06-01 est:10", "delta"}},
+		{"priority,-created", []string{"alpha +api", "beta +web due:2026-06-01 est:10", "gamma +Web +api due:2026-05-01 est:9", "delta"}},
+		{"-due", []string{"beta +web due:2026-06-01 est:10", "gamma +Web +api due:2026-05-01 est:9", "alpha +api", "delta"}},
+		{"project,text", []string{"alpha +api", "gamma +Web +api due:2026-05-01 est:9", "beta +web due:2026-06-01 est:10", "delta"}},
+		{"key:est", []string{"gamma +Web +api due:2026-05-01 est:9", "beta +web due:2026-06-01 est:10", "alpha +api", "delta"}},
+		{"-id", []string{"delta", "gamma +Web +api due:2026-05-01 est:9", "alpha +api", "beta +web due:2026-06-01 est:10"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.sort, func(t *testing.T) {
+			path := writeRawFile(t, content)
+			var stdout, stderr bytes.Buffer
+
+			if code := run([]string{"-f", path, "-s", tc.sort, "-format", "{{.Message}}"}, nil, &stdout, &stderr); code != 0 {
+				t.Fatalf("run exited %d: %s", code, stderr.String())
+			}
+			if got := outputLines(stdout.String()); !sliceEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRun_SortUnknownField(t *testing.T) {
+	path := writeRawFile(t, "first\n")
+
+	for _, sort := range []string{"priorty", "priority,", "key:", "-"} {
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"-f", path, "-s", sort}, nil, &stdout, &stderr); code != 1 {
+			t.Errorf("-s %q exited %d, want 1", sort, code)
+		}
+		if !strings.Contains(stderr.String(), "unknown sort field") {
+			t.Errorf("-s %q: stderr = %q", sort, stderr.String())
+		}
+	}
+}
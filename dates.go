@@ -0,0 +1,104 @@
+package todo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDate parses a date as written by a person, relative to the day of
+// now. It accepts:
+//
+//	2026-05-23                a date in todo.txt form
+//	today, tomorrow, yesterday
+//	mon, tuesday, ...         the next such day after today
+//	+3d, -1w, 2m, +1y         a number of days, weeks, months or years from today
+//	eow, eom, eoy             the end (last day) of this week, month or year
+//
+// Weeks end on Sunday. Names are matched ignoring case and weekdays may be
+// shortened to three letters or more.
+func ParseDate(s string, now time.Time) (time.Time, error) {
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+
+	lower := strings.ToLower(s)
+	switch lower {
+	case "today":
+		return today, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	case "eow":
+		return today.AddDate(0, 0, (7-int(today.Weekday()))%7), nil
+	case "eom":
+		return time.Date(y, m+1, 0, 0, 0, 0, 0, time.Local), nil
+	case "eoy":
+		return time.Date(y, time.December, 31, 0, 0, 0, 0, time.Local), nil
+	}
+
+	if date, err := time.ParseInLocation(dateLayout, s, time.Local); err == nil {
+		return date, nil
+	}
+	if len(lower) >= 3 {
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if strings.HasPrefix(strings.ToLower(day.String()), lower) {
+				days := (int(day) - int(today.Weekday()) + 6) % 7
+				return today.AddDate(0, 0, days+1), nil
+			}
+		}
+	}
+	if n, unit, ok := cutInterval(lower); ok {
+		return addInterval(today, n, unit), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q: want YYYY-MM-DD, a day name, an offset like +3d, or eom", s)
+}
+
+// cutInterval parses an interval such as "+3d", "-1w" or "2m" into a signed
+// count and a unit of d, w, m or y.
+func cutInterval(s string) (n int, unit rune, ok bool) {
+	sign := 1
+	if rest, neg := strings.CutPrefix(s, "-"); neg {
+		s, sign = rest, -1
+	} else {
+		s = strings.TrimPrefix(s, "+")
+	}
+	if len(s) < 2 {
+		return 0, 0, false
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 || s[0] == '+' || s[0] == '-' {
+		return 0, 0, false
+	}
+	switch unit = rune(s[len(s)-1]); unit {
+	case 'd', 'w', 'm', 'y':
+		return sign * n, unit, true
+	}
+	return 0, 0, false
+}
+
+// addInterval adds n days, weeks, months or years to t. Adding months or
+// years to the end of a month gives the end of the shorter month if need
+// be, so a month after 31 January is 28 or 29 February.
+func addInterval(t time.Time, n int, unit rune) time.Time {
+	switch unit {
+	case 'd':
+		return t.AddDate(0, 0, n)
+	case 'w':
+		return t.AddDate(0, 0, 7*n)
+	case 'm':
+		return addMonths(t, n)
+	case 'y':
+		return addMonths(t, 12*n)
+	}
+	return t
+}
+
+// addMonths adds n months to t, keeping to the last day of the month rather
+// than overflowing into the next.
+func addMonths(t time.Time, n int) time.Time {
+	y, m, d := t.Date()
+	last := time.Date(y, m+time.Month(n)+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	return time.Date(y, m+time.Month(n), min(d, last), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
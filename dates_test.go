@@ -0,0 +1,71 @@
+package todo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	now := time.Date(2026, 5, 23, 18, 0, 0, 0, time.Local) // a Saturday
+
+	tests := []struct {
+		in   string
+		want string // "" for an error
+	}{
+		{"2026-07-04", "2026-07-04"},
+		{"today", "2026-05-23"},
+		{"Tomorrow", "2026-05-24"},
+		{"yesterday", "2026-05-22"},
+		{"fri", "2026-05-29"},
+		{"friday", "2026-05-29"},
+		{"sat", "2026-05-30"}, // the next Saturday, not today
+		{"sun", "2026-05-24"},
+		{"+3d", "2026-05-26"},
+		{"3d", "2026-05-26"},
+		{"-1w", "2026-05-16"},
+		{"+1m", "2026-06-23"},
+		{"+1y", "2027-05-23"},
+		{"eow", "2026-05-24"},
+		{"eom", "2026-05-31"},
+		{"eoy", "2026-12-31"},
+		{"fr", ""},
+		{"fridays", ""},
+		{"soon", ""},
+		{"+d", ""},
+		{"+-1d", ""},
+		{"2026-02-30", ""},
+	}
+	for _, tc := range tests {
+		got, err := ParseDate(tc.in, now)
+		if tc.want == "" {
+			if err == nil {
+				t.Errorf("ParseDate(%q) = %v, want error", tc.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseDate(%q): %v", tc.in, err)
+			continue
+		}
+		if got.Format(dateLayout) != tc.want {
+			t.Errorf("ParseDate(%q) = %s, want %s", tc.in, got.Format(dateLayout), tc.want)
+		}
+	}
+}
+
+func TestItem_ResolveDates(t *testing.T) {
+	now := time.Date(2026, 5, 23, 18, 0, 0, 0, time.Local)
+	var item Item
+	if err := item.UnmarshalText([]byte("pay rent due:eom t:+3d rec:1m note:tomorrow due2:fri")); err != nil {
+		t.Fatal(err)
+	}
+
+	item.ResolveDates(now)
+	want := "pay rent due:2026-05-31 t:2026-05-26 rec:1m note:tomorrow due2:fri"
+	if item.Message != want {
+		t.Errorf("Message = %q, want %q", item.Message, want)
+	}
+	if due, ok := item.Due(); !ok || due.Format(dateLayout) != "2026-05-31" {
+		t.Errorf("Due() = %v, %v; want 2026-05-31", due, ok)
+	}
+}
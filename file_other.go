@@ -0,0 +1,16 @@
+//go:build !unix
+
+package todo
+
+import "os"
+
+// preserveOwner is a no-op: file ownership is not carried by a uid and gid on
+// this platform.
+func preserveOwner(*os.File, os.FileInfo) error {
+	return nil
+}
+
+// syncDir is a no-op: directories cannot be synced on this platform.
+func syncDir(string) error {
+	return nil
+}
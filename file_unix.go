@@ -0,0 +1,38 @@
+//go:build unix
+
+package todo
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// preserveOwner gives f the owner and group of existing. Only root may give a
+// file away, so a permission error is ignored: the file is then owned by
+// the writer, as any newly created file would be.
+func preserveOwner(f *os.File, existing os.FileInfo) error {
+	st, ok := existing.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	err := f.Chown(int(st.Uid), int(st.Gid))
+	if errors.Is(err, os.ErrPermission) {
+		return nil
+	}
+	return err
+}
+
+// syncDir flushes the directory entry changes in dir, such as a rename, to
+// disk.
+func syncDir(dir string) error {
+	d, err := os.Open(dir) // #nosec G304 -- dir holds the caller's todo file.
+	if err != nil {
+		return err
+	}
+	syncErr := d.Sync()
+	if closeErr := d.Close(); closeErr != nil && syncErr == nil {
+		syncErr = closeErr
+	}
+	return syncErr
+}
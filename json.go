@@ -0,0 +1,105 @@
+package todo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// itemJSON is the JSON form of an Item. Priorities are letters and dates are
+// YYYY-MM-DD, both empty when unset.
+type itemJSON struct {
+	ID            Id                `json:"id"`
+	Message       string            `json:"description"`
+	Done          bool              `json:"done"`
+	Priority      string            `json:"priority"`
+	CreatedDate   string            `json:"created-date"`
+	CompletedDate string            `json:"completed-date"`
+	Projects      []string          `json:"projects"`
+	Contexts      []string          `json:"contexts"`
+	SpecialKeys   map[string]string `json:"special-keys"`
+}
+
+// MarshalJSON returns the item as a JSON object with the keys given by the
+// Item field tags. Projects, contexts and special keys are always present,
+// empty if the item has none.
+func (i Item) MarshalJSON() ([]byte, error) {
+	v := itemJSON{
+		ID:          i.ID,
+		Message:     i.Message,
+		Done:        i.Done,
+		Projects:    i.Projects,
+		Contexts:    i.Contexts,
+		SpecialKeys: i.SpecialKeys,
+	}
+	if i.Priority.Valid() {
+		v.Priority = string(rune(i.Priority))
+	}
+	if !i.CreatedDate.IsZero() {
+		v.CreatedDate = i.CreatedDate.Format(dateLayout)
+	}
+	if !i.CompletedDate.IsZero() {
+		v.CompletedDate = i.CompletedDate.Format(dateLayout)
+	}
+	if v.Projects == nil {
+		v.Projects = []string{}
+	}
+	if v.Contexts == nil {
+		v.Contexts = []string{}
+	}
+	if v.SpecialKeys == nil {
+		v.SpecialKeys = map[string]string{}
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON parses an item in the form written by MarshalJSON. The
+// projects, contexts and special keys are derived from the description, so
+// those keys may be left out, as may the id.
+func (i *Item) UnmarshalJSON(data []byte) error {
+	var v itemJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if strings.TrimSpace(v.Message) == "" {
+		return errors.New("item has no description")
+	}
+	if strings.ContainsAny(v.Message, "\r\n") {
+		return errors.New("item description contains a line break")
+	}
+
+	item := Item{ID: v.ID, Done: v.Done}
+	if v.Priority != "" {
+		p, err := ParsePriority(v.Priority)
+		if err != nil {
+			return err
+		}
+		item.Priority = p
+	}
+	var err error
+	if item.CreatedDate, err = parseJSONDate("created-date", v.CreatedDate); err != nil {
+		return err
+	}
+	if item.CompletedDate, err = parseJSONDate("completed-date", v.CompletedDate); err != nil {
+		return err
+	}
+	item.SetMessage(v.Message)
+
+	*i = item
+	return nil
+}
+
+// parseJSONDate parses the YYYY-MM-DD date of the named key, returning the
+// zero time for an empty value.
+func parseJSONDate(key, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.ParseInLocation(dateLayout, value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q: want YYYY-MM-DD", key, value)
+	}
+	return date, nil
+}
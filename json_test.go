@@ -0,0 +1,79 @@
+package todo
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestItem_MarshalJSON(t *testing.T) {
+	var item Item
+	if err := item.UnmarshalText([]byte("x (A) 2026-05-23 2026-05-01 pay rent +home @desk due:2026-05-31")); err != nil {
+		t.Fatal(err)
+	}
+	item.ID = 3
+
+	got, err := json.Marshal(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id":3,"description":"pay rent +home @desk due:2026-05-31","done":true,"priority":"A",` +
+		`"created-date":"2026-05-01","completed-date":"2026-05-23","projects":["home"],"contexts":["desk"],` +
+		`"special-keys":{"due":"2026-05-31"}}`
+	if string(got) != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	got, err = json.Marshal(Item{Message: "plain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `{"id":0,"description":"plain","done":false,"priority":"","created-date":"","completed-date":"",` +
+		`"projects":[],"contexts":[],"special-keys":{}}`
+	if string(got) != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestItem_JSONRoundTrip(t *testing.T) {
+	var item Item
+	if err := item.UnmarshalText([]byte("(B) 2026-05-01 call mum @phone +family rec:1w")); err != nil {
+		t.Fatal(err)
+	}
+	item.ID = 7
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Item
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, item) {
+		t.Errorf("got %+v, want %+v", got, item)
+	}
+}
+
+func TestItem_UnmarshalJSON(t *testing.T) {
+	var item Item
+	if err := json.Unmarshal([]byte(`{"description":"buy milk @shop","priority":"c"}`), &item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Priority != 'C' || !reflect.DeepEqual(item.Contexts, []string{"shop"}) {
+		t.Errorf("got %+v, want priority C and context shop", item)
+	}
+
+	for _, in := range []string{
+		`{"description":""}`,
+		`{"description":"a\nb"}`,
+		`{"description":"x","priority":"AB"}`,
+		`{"description":"x","created-date":"May 1"}`,
+		`"x (A) text"`,
+	} {
+		var item Item
+		if err := json.Unmarshal([]byte(in), &item); err == nil {
+			t.Errorf("Unmarshal(%s) = %+v, want error", in, item)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package todo
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Due returns the date in the item's due: key. ok is false if the item has
+// no due: key or its value is not a YYYY-MM-DD date.
+func (i *Item) Due() (date time.Time, ok bool) {
+	return i.dateKey("due")
+}
+
+// Threshold returns the date in the item's t: key, before which the item is
+// not yet actionable. ok is false if the item has no t: key or its value is
+// not a YYYY-MM-DD date.
+func (i *Item) Threshold() (date time.Time, ok bool) {
+	return i.dateKey("t")
+}
+
+// Recurrence returns the interval in the item's rec: key. ok is false if the
+// item has no rec: key or its value is not a valid interval.
+func (i *Item) Recurrence() (rec Recurrence, ok bool) {
+	value, ok := i.SpecialKeys["rec"]
+	if !ok {
+		return Recurrence{}, false
+	}
+	rec, err := ParseRecurrence(value)
+	return rec, err == nil
+}
+
+// Overdue reports whether the item is open and its due date is before the
+// day of now.
+func (i *Item) Overdue(now time.Time) bool {
+	due, ok := i.Due()
+	if i.Done || !ok {
+		return false
+	}
+	y, m, d := now.Date()
+	return due.Before(time.Date(y, m, d, 0, 0, 0, 0, time.Local))
+}
+
+// SetKey sets the value of the key:value pair key in the item's message,
+// replacing any pairs with that key in place or else adding one at the end.
+func (i *Item) SetKey(key, value string) {
+	pair := key + ":" + value
+	var msg strings.Builder
+	found := false
+	rest := i.Message
+	for rest != "" {
+		start := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsSpace(r) })
+		if start < 0 {
+			msg.WriteString(rest)
+			break
+		}
+		end := strings.IndexFunc(rest[start:], unicode.IsSpace)
+		if end < 0 {
+			end = len(rest)
+		} else {
+			end += start
+		}
+		word := rest[start:end]
+		if k, _, ok := cutPair(word); ok && k == key {
+			word, found = pair, true
+		}
+		msg.WriteString(rest[:start])
+		msg.WriteString(word)
+		rest = rest[end:]
+	}
+	if !found {
+		if msg.Len() > 0 {
+			msg.WriteString(" ")
+		}
+		msg.WriteString(pair)
+	}
+	i.SetMessage(msg.String())
+}
+
+// Next returns the next occurrence of a recurring item completed on the day
+// of completed: an open copy of it created that day, with its due: date
+// moved on by its rec: interval. Strict recurrences count from the old due
+// date, others from the completion day. A t: threshold date keeps its
+// distance from the due date, or if there is no due date is moved on in
+// the same way. An item with neither is given a due date. ok is false if the
+// item has no valid rec: key.
+func (i *Item) Next(completed time.Time) (next Item, ok bool) {
+	rec, ok := i.Recurrence()
+	if !ok {
+		return Item{}, false
+	}
+	y, m, d := completed.Date()
+	completed = time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+
+	next = Item{Priority: i.Priority, CreatedDate: completed}
+	next.SetMessage(i.Message)
+
+	due, hasDue := i.Due()
+	threshold, hasThreshold := i.Threshold()
+	switch {
+	case hasDue:
+		base := completed
+		if rec.Strict {
+			base = due
+		}
+		newDue := rec.Next(base)
+		next.SetKey("due", newDue.Format(dateLayout))
+		if hasThreshold {
+			days := int(math.Round(due.Sub(threshold).Hours() / 24))
+			next.SetKey("t", newDue.AddDate(0, 0, -days).Format(dateLayout))
+		}
+	case hasThreshold:
+		base := completed
+		if rec.Strict {
+			base = threshold
+		}
+		next.SetKey("t", rec.Next(base).Format(dateLayout))
+	default:
+		next.SetKey("due", rec.Next(completed).Format(dateLayout))
+	}
+	return next, true
+}
+
+// dateKeys are the keys whose values are dates.
+var dateKeys = []string{"due", "t"}
+
+// ResolveDates rewrites the values of the item's due: and t: keys from any
+// form ParseDate accepts, such as due:fri or t:+3d, to YYYY-MM-DD dates
+// relative to the day of now. Values that are not dates are left alone.
+func (i *Item) ResolveDates(now time.Time) {
+	for _, key := range dateKeys {
+		value, ok := i.SpecialKeys[key]
+		if !ok {
+			continue
+		}
+		date, err := ParseDate(value, now)
+		if err != nil {
+			continue
+		}
+		if resolved := date.Format(dateLayout); resolved != value {
+			i.SetKey(key, resolved)
+		}
+	}
+}
+
+// dateKey parses the value of the key:value pair key as a date.
+func (i *Item) dateKey(key string) (time.Time, bool) {
+	value, ok := i.SpecialKeys[key]
+	if !ok {
+		return time.Time{}, false
+	}
+	date, err := time.ParseInLocation(dateLayout, value, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// Recurrence is how often a task repeats, as given by a rec: key such as
+// rec:1w or rec:+3d.
+type Recurrence struct {
+	// Strict recurrences, written with a leading "+", repeat from the
+	// task's due date; others repeat from the day it was completed.
+	Strict bool
+	N      int
+	Unit   rune // 'd' days, 'w' weeks, 'm' months or 'y' years
+}
+
+// ParseRecurrence parses an interval such as "1w", "+1m" or "3d": an
+// optional "+", a whole number and a unit of d, w, m or y.
+func ParseRecurrence(s string) (Recurrence, error) {
+	var rec Recurrence
+	var rest string
+	rest, rec.Strict = strings.CutPrefix(s, "+")
+	if len(rest) < 2 {
+		return Recurrence{}, fmt.Errorf("invalid recurrence %q: want a number and d, w, m or y, like 1w", s)
+	}
+	n, err := strconv.Atoi(rest[:len(rest)-1])
+	if err != nil || n < 1 {
+		return Recurrence{}, fmt.Errorf("invalid recurrence %q: want a number and d, w, m or y, like 1w", s)
+	}
+	rec.N = n
+	switch rec.Unit = rune(rest[len(rest)-1]); rec.Unit {
+	case 'd', 'w', 'm', 'y':
+	default:
+		return Recurrence{}, fmt.Errorf("invalid recurrence %q: want a number and d, w, m or y, like 1w", s)
+	}
+	return rec, nil
+}
+
+// String returns the recurrence in the form ParseRecurrence accepts.
+func (r Recurrence) String() string {
+	s := strconv.Itoa(r.N) + string(r.Unit)
+	if r.Strict {
+		s = "+" + s
+	}
+	return s
+}
+
+// Next returns the date one interval after t. Adding months or years to the
+// end of a month gives the end of the shorter month if need be, so a month
+// after 31 January is 28 or 29 February.
+func (r Recurrence) Next(t time.Time) time.Time {
+	return addInterval(t, r.N, r.Unit)
+}
@@ -0,0 +1,197 @@
+package todo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestItem_DueThreshold(t *testing.T) {
+	var item Item
+	if err := item.UnmarshalText([]byte("pay rent due:2026-06-01 t:2026-05-25 rec:+1m")); err != nil {
+		t.Fatal(err)
+	}
+
+	if due, ok := item.Due(); !ok || due.Format(dateLayout) != "2026-06-01" {
+		t.Errorf("Due() = %v, %v; want 2026-06-01", due, ok)
+	}
+	if th, ok := item.Threshold(); !ok || th.Format(dateLayout) != "2026-05-25" {
+		t.Errorf("Threshold() = %v, %v; want 2026-05-25", th, ok)
+	}
+	if rec, ok := item.Recurrence(); !ok || rec != (Recurrence{Strict: true, N: 1, Unit: 'm'}) {
+		t.Errorf("Recurrence() = %v, %v; want +1m", rec, ok)
+	}
+
+	item.SetMessage("no dates due:soon rec:often")
+	if _, ok := item.Due(); ok {
+		t.Error("Due() ok for due:soon")
+	}
+	if _, ok := item.Threshold(); ok {
+		t.Error("Threshold() ok without t:")
+	}
+	if _, ok := item.Recurrence(); ok {
+		t.Error("Recurrence() ok for rec:often")
+	}
+}
+
+func TestItem_Overdue(t *testing.T) {
+	now := time.Date(2026, 5, 23, 18, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"pay rent due:2026-05-22", true},
+		{"pay rent due:2026-05-23", false},
+		{"pay rent due:2026-06-01", false},
+		{"x 2026-05-23 pay rent due:2026-05-01", false},
+		{"pay rent", false},
+	}
+	for _, tc := range tests {
+		var item Item
+		if err := item.UnmarshalText([]byte(tc.line)); err != nil {
+			t.Fatal(err)
+		}
+		if got := item.Overdue(now); got != tc.want {
+			t.Errorf("Overdue(%q) = %v, want %v", tc.line, got, tc.want)
+		}
+	}
+}
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  Recurrence
+		valid bool
+	}{
+		{"1w", Recurrence{N: 1, Unit: 'w'}, true},
+		{"+1m", Recurrence{Strict: true, N: 1, Unit: 'm'}, true},
+		{"3d", Recurrence{N: 3, Unit: 'd'}, true},
+		{"10y", Recurrence{N: 10, Unit: 'y'}, true},
+		{"", Recurrence{}, false},
+		{"w", Recurrence{}, false},
+		{"0d", Recurrence{}, false},
+		{"-1d", Recurrence{}, false},
+		{"2x", Recurrence{}, false},
+	}
+	for _, tc := range tests {
+		got, err := ParseRecurrence(tc.in)
+		if (err == nil) != tc.valid || got != tc.want {
+			t.Errorf("ParseRecurrence(%q) = %v, %v; want %v, valid %v", tc.in, got, err, tc.want, tc.valid)
+			continue
+		}
+		if tc.valid && got.String() != tc.in {
+			t.Errorf("String() = %q, want %q", got.String(), tc.in)
+		}
+	}
+}
+
+func TestRecurrence_Next(t *testing.T) {
+	date := func(s string) time.Time {
+		d, err := time.ParseInLocation(dateLayout, s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	tests := []struct {
+		rec  string
+		from string
+		want string
+	}{
+		{"3d", "2026-05-30", "2026-06-02"},
+		{"2w", "2026-05-23", "2026-06-06"},
+		{"1m", "2026-01-31", "2026-02-28"},
+		{"1m", "2028-01-31", "2028-02-29"},
+		{"1m", "2026-12-15", "2027-01-15"},
+		{"1y", "2028-02-29", "2029-02-28"},
+	}
+	for _, tc := range tests {
+		rec, err := ParseRecurrence(tc.rec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := rec.Next(date(tc.from)).Format(dateLayout); got != tc.want {
+			t.Errorf("%s after %s = %s, want %s", tc.rec, tc.from, got, tc.want)
+		}
+	}
+}
+
+func TestItem_SetKey(t *testing.T) {
+	tests := []struct {
+		msg, key, value string
+		want            string
+	}{
+		{"pay rent due:2026-05-01 @home", "due", "2026-06-01", "pay rent due:2026-06-01 @home"},
+		{"pay rent @home", "due", "2026-06-01", "pay rent @home due:2026-06-01"},
+		{"", "t", "2026-06-01", "t:2026-06-01"},
+		{"see http://example.com  due:x", "due", "y", "see http://example.com  due:y"},
+	}
+	for _, tc := range tests {
+		item := Item{}
+		item.SetMessage(tc.msg)
+		item.SetKey(tc.key, tc.value)
+		if item.Message != tc.want {
+			t.Errorf("SetKey(%q, %q) on %q = %q, want %q", tc.key, tc.value, tc.msg, item.Message, tc.want)
+		}
+		if item.SpecialKeys[tc.key] != tc.value {
+			t.Errorf("SpecialKeys[%q] = %q, want %q", tc.key, item.SpecialKeys[tc.key], tc.value)
+		}
+	}
+}
+
+func TestItem_Next(t *testing.T) {
+	completed := time.Date(2026, 5, 23, 17, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name string
+		line string
+		want string // "" if the item does not recur
+	}{
+		{
+			name: "from completion",
+			line: "x 2026-05-23 2026-05-01 water plants due:2026-05-20 rec:1w",
+			want: "2026-05-23 water plants due:2026-05-30 rec:1w",
+		},
+		{
+			name: "strict from due date",
+			line: "(A) 2026-05-01 pay rent due:2026-05-01 rec:+1m @home",
+			want: "(A) 2026-05-23 pay rent due:2026-06-01 rec:+1m @home",
+		},
+		{
+			name: "threshold keeps its distance",
+			line: "pay rent due:2026-05-01 t:2026-04-25 rec:+1m",
+			want: "2026-05-23 pay rent due:2026-06-01 t:2026-05-26 rec:+1m",
+		},
+		{
+			name: "threshold only",
+			line: "review budget t:2026-05-01 rec:+3d",
+			want: "2026-05-23 review budget t:2026-05-04 rec:+3d",
+		},
+		{
+			name: "no dates",
+			line: "stretch rec:3d",
+			want: "2026-05-23 stretch rec:3d due:2026-05-26",
+		},
+		{name: "no rec", line: "stretch due:2026-05-26"},
+		{name: "invalid rec", line: "stretch rec:often"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var item Item
+			if err := item.UnmarshalText([]byte(tc.line)); err != nil {
+				t.Fatal(err)
+			}
+			next, ok := item.Next(completed)
+			if ok != (tc.want != "") {
+				t.Fatalf("Next ok = %v, want %v", ok, tc.want != "")
+			}
+			if !ok {
+				return
+			}
+			if text, _ := next.MarshalText(); string(text) != tc.want {
+				t.Errorf("Next = %q, want %q", text, tc.want)
+			}
+		})
+	}
+}
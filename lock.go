@@ -0,0 +1,72 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrLocked is returned by LockFile when another process holds the lock for
+// longer than the timeout.
+var ErrLocked = errors.New("file is locked by another process")
+
+// lockPollInterval is how often LockFile retries a lock held elsewhere.
+const lockPollInterval = 20 * time.Millisecond
+
+// FileLock is an advisory lock on a todo.txt file, taken by LockFile.
+// It only excludes other processes that also use LockFile.
+type FileLock struct {
+	f *os.File
+}
+
+// LockFile takes an exclusive advisory lock on the file at lockPath,
+// creating it and its directory if need be, and waits up to timeout for
+// another holder to release it. Every process changing a todo.txt file must
+// lock the same lockPath for it. The lock cannot be on the todo file itself,
+// because WriteFile replaces it, and is best kept out of its directory, where
+// sync clients and dotfile repositories would pick it up. On platforms
+// without advisory locks LockFile always succeeds, leaving WriteFile's
+// conflict check as the only protection.
+func LockFile(lockPath string, timeout time.Duration) (*FileLock, error) {
+	lockPath = filepath.Clean(lockPath)
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0o750); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := tryLock(f)
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		if locked {
+			return &FileLock{f: f}, nil
+		}
+		if time.Now().After(deadline) {
+			_ = f.Close()
+			return nil, fmt.Errorf("%w: %s", ErrLocked, lockPath)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// Unlock releases the lock. Calling Unlock more than once is a no-op.
+func (l *FileLock) Unlock() error {
+	if l.f == nil {
+		return nil
+	}
+	f := l.f
+	l.f = nil
+	unlockErr := unlock(f)
+	if closeErr := f.Close(); closeErr != nil && unlockErr == nil {
+		unlockErr = closeErr
+	}
+	return unlockErr
+}
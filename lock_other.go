@@ -0,0 +1,15 @@
+//go:build !unix
+
+package todo
+
+import "os"
+
+// tryLock always succeeds: advisory locks are not supported on this platform.
+func tryLock(*os.File) (bool, error) {
+	return true, nil
+}
+
+// unlock is a no-op on platforms without advisory locks.
+func unlock(*os.File) error {
+	return nil
+}
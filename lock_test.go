@@ -0,0 +1,44 @@
+//go:build unix
+
+package todo
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locks", "todo.lock")
+
+	lock, err := LockFile(path, time.Second)
+	if err != nil {
+		t.Fatalf("LockFile: %v", err)
+	}
+
+	if _, err := LockFile(path, 50*time.Millisecond); !errors.Is(err, ErrLocked) {
+		t.Fatalf("second LockFile error = %v, want ErrLocked", err)
+	}
+
+	// A waiting locker gets the lock once it is released.
+	acquired := make(chan error, 1)
+	go func() {
+		second, err := LockFile(path, 5*time.Second)
+		if err == nil {
+			err = second.Unlock()
+		}
+		acquired <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if err := lock.Unlock(); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if err := <-acquired; err != nil {
+		t.Fatalf("waiting LockFile: %v", err)
+	}
+
+	if err := lock.Unlock(); err != nil {
+		t.Errorf("second Unlock: %v", err)
+	}
+}
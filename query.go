@@ -0,0 +1,467 @@
+package todo
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Query is a filter over items, usually parsed from a query string by
+// ParseQuery.
+type Query interface {
+	Match(item Item) bool
+}
+
+// And matches items that match both Left and Right.
+type And struct{ Left, Right Query }
+
+func (q And) Match(item Item) bool { return q.Left.Match(item) && q.Right.Match(item) }
+
+// Or matches items that match Left, Right or both.
+type Or struct{ Left, Right Query }
+
+func (q Or) Match(item Item) bool { return q.Left.Match(item) || q.Right.Match(item) }
+
+// Not matches items that do not match Query.
+type Not struct{ Query Query }
+
+func (q Not) Match(item Item) bool { return !q.Query.Match(item) }
+
+// Text matches items whose todo.txt line contains the text.
+type Text string
+
+func (q Text) Match(item Item) bool {
+	line, _ := item.MarshalText()
+	return strings.Contains(string(line), string(q))
+}
+
+// FoldText matches items whose todo.txt line contains the text, ignoring
+// case.
+type FoldText string
+
+func (q FoldText) Match(item Item) bool {
+	line, _ := item.MarshalText()
+	return strings.Contains(strings.ToLower(string(line)), strings.ToLower(string(q)))
+}
+
+// Context matches items with the context, given without its "@".
+type Context string
+
+func (q Context) Match(item Item) bool {
+	for _, c := range item.Contexts {
+		if c == string(q) {
+			return true
+		}
+	}
+	return false
+}
+
+// Project matches items with the project, given without its "+".
+type Project string
+
+func (q Project) Match(item Item) bool {
+	for _, p := range item.Projects {
+		if p == string(q) {
+			return true
+		}
+	}
+	return false
+}
+
+// Op is a comparison operator in a Compare query.
+type Op string
+
+const (
+	OpEq Op = "="
+	OpNe Op = "!="
+	OpLt Op = "<"
+	OpLe Op = "<="
+	OpGt Op = ">"
+	OpGe Op = ">="
+)
+
+// Compare matches items whose Field compares to Value by Op. Values that
+// are both whole numbers compare as numbers, anything else as text, so
+// dates in YYYY-MM-DD form and priority letters compare in order: pri<=B
+// matches priorities A and B. Items without the field never match.
+//
+// Field is "pri" for the priority, "created" or "completed" for those dates,
+// "text" for the description, or else the key of a key:value pair.
+type Compare struct {
+	Field string
+	Op    Op
+	Value string
+}
+
+func (q Compare) Match(item Item) bool {
+	value, ok := fieldValue(item, q.Field)
+	if !ok {
+		return false
+	}
+
+	var c int
+	a, errA := strconv.Atoi(value)
+	b, errB := strconv.Atoi(q.Value)
+	if errA == nil && errB == nil {
+		c = cmp.Compare(a, b)
+	} else {
+		c = strings.Compare(value, q.Value)
+	}
+
+	switch q.Op {
+	case OpEq:
+		return c == 0
+	case OpNe:
+		return c != 0
+	case OpLt:
+		return c < 0
+	case OpLe:
+		return c <= 0
+	case OpGt:
+		return c > 0
+	case OpGe:
+		return c >= 0
+	}
+	return false
+}
+
+// Regexp matches items whose Field, named as for Compare, matches the
+// regular expression.
+type Regexp struct {
+	Field  string
+	Regexp *regexp.Regexp
+}
+
+func (q Regexp) Match(item Item) bool {
+	value, ok := fieldValue(item, q.Field)
+	return ok && q.Regexp.MatchString(value)
+}
+
+// IgnoreCase returns q with its Text and Regexp terms changed to ignore case.
+// Contexts, projects and comparisons still match exactly.
+func IgnoreCase(q Query) Query {
+	switch q := q.(type) {
+	case And:
+		return And{IgnoreCase(q.Left), IgnoreCase(q.Right)}
+	case Or:
+		return Or{IgnoreCase(q.Left), IgnoreCase(q.Right)}
+	case Not:
+		return Not{IgnoreCase(q.Query)}
+	case Text:
+		return FoldText(q)
+	case Regexp:
+		return Regexp{Field: q.Field, Regexp: regexp.MustCompile("(?i)" + q.Regexp.String())}
+	}
+	return q
+}
+
+// fieldValue returns the text of the named field of item, and false if the
+// item does not have it.
+func fieldValue(item Item, field string) (string, bool) {
+	switch field {
+	case "pri":
+		return string(rune(item.Priority)), item.Priority.Valid()
+	case "created":
+		return item.CreatedDate.Format(dateLayout), !item.CreatedDate.IsZero()
+	case "completed":
+		return item.CompletedDate.Format(dateLayout), !item.CompletedDate.IsZero()
+	case "text":
+		return item.Message, true
+	}
+	value, ok := item.SpecialKeys[field]
+	return value, ok
+}
+
+// queryFields are the fields with a meaning of their own in queries; any
+// other field is a key:value pair.
+var queryFields = map[string]bool{"pri": true, "created": true, "completed": true, "text": true, "due": true, "t": true}
+
+// dateFields are the fields whose query values are dates, in any form
+// ParseDate accepts.
+var dateFields = map[string]bool{"created": true, "completed": true, "due": true, "t": true}
+
+// ParseQuery parses a query string. Terms are separated by spaces and
+// combined with "and", "or" and "not" (upper case or lower), with
+// parentheses for grouping. "not" binds tightest and "or" loosest, so
+// "not @a and @b or @c" is "((not @a) and @b) or @c". Terms next to each
+// other without an operator are combined with "and", and a term starting
+// with "-" is negated, so "-@home" is the same as "not @home". A term is one
+// of:
+//
+//	@context       the item has the context
+//	+project       the item has the project
+//	field<op>value a Compare, with op one of = != < <= > >=
+//	field~regexp   a Regexp
+//	anything else  a Text, matching part of the item's line
+//
+// A comparison on a key:value pair, such as "estimate>3", also matches
+// items containing the term as text, and a term with no value after its
+// operator, such as "x=", or with "!" alone, such as "bob!", is Text. Text
+// and values containing spaces or parentheses can be put in double quotes,
+// and a quoted term is always Text, so `"x=1"` is only ever text. Dates in
+// date fields are parsed by ParseDate against now, so with the date in now
+// being 2026-05-23, "due<today" is the same as "due<2026-05-23".
+func ParseQuery(s string, now time.Time) (Query, error) {
+	tokens, err := tokenizeQuery(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query %q: %w", s, err)
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("invalid query %q: empty", s)
+	}
+
+	p := &queryParser{tokens: tokens, now: now}
+	q, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid query %q: %w", s, err)
+	}
+	return q, nil
+}
+
+// queryToken is a word or parenthesis in a query string. quoted words are
+// never operators.
+type queryToken struct {
+	text   string
+	quoted bool
+}
+
+// tokenizeQuery splits s into words and parentheses. A parenthesis within a
+// word, as in a regexp, is part of the word if it is balanced. Double quotes,
+// which may start a word or appear within one, group text containing spaces
+// and parentheses, and a backslash in quotes escapes the next character.
+func tokenizeQuery(s string) ([]queryToken, error) {
+	var tokens []queryToken
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		switch r := runes[i]; {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, queryToken{text: string(r)})
+			i++
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '(':
+			// Negating a group: the "-" is a token of its own.
+			tokens = append(tokens, queryToken{text: "-"})
+			i++
+		default:
+			tok := queryToken{quoted: r == '"'}
+			var word strings.Builder
+			depth := 0 // parentheses opened within the word
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && (runes[i] != ')' || depth > 0) {
+				switch runes[i] {
+				case '(':
+					depth++
+				case ')':
+					depth--
+				}
+				if runes[i] != '"' {
+					word.WriteRune(runes[i])
+					i++
+					continue
+				}
+				i++
+				for ; i < len(runes) && runes[i] != '"'; i++ {
+					if runes[i] == '\\' && i+1 < len(runes) {
+						i++
+					}
+					word.WriteRune(runes[i])
+				}
+				if i == len(runes) {
+					return nil, errors.New("missing closing quote")
+				}
+				i++
+			}
+			tok.text = word.String()
+			tokens = append(tokens, tok)
+		}
+	}
+	return tokens, nil
+}
+
+// queryParser is a recursive descent parser over query tokens.
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+	now    time.Time
+}
+
+// peek returns the next token, or the zero token at the end.
+func (p *queryParser) peek() queryToken {
+	if p.pos >= len(p.tokens) {
+		return queryToken{}
+	}
+	return p.tokens[p.pos]
+}
+
+// keyword reports whether the next token is the unquoted operator word.
+func (p *queryParser) keyword(word string) bool {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].quoted {
+		return false
+	}
+	return strings.EqualFold(p.tokens[p.pos].text, word)
+}
+
+// punct reports whether the next token is the unquoted parenthesis.
+func (p *queryParser) punct(text string) bool {
+	return p.pos < len(p.tokens) && !p.tokens[p.pos].quoted && p.tokens[p.pos].text == text
+}
+
+func (p *queryParser) parseOr() (Query, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (Query, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.pos < len(p.tokens) && !p.keyword("or") && !p.punct(")") {
+		if p.keyword("and") {
+			p.pos++
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = And{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseNot() (Query, error) {
+	if tok := p.peek(); !tok.quoted && tok.text != "" && tok.text[0] == '-' {
+		if tok.text == "-" {
+			p.pos++
+		} else {
+			p.tokens[p.pos].text = tok.text[1:]
+		}
+		q, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return Not{q}, nil
+	}
+	if p.keyword("not") {
+		p.pos++
+		q, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return Not{q}, nil
+	}
+	return p.parseTerm()
+}
+
+func (p *queryParser) parseTerm() (Query, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, errors.New("unexpected end")
+	}
+	if p.punct("(") {
+		p.pos++
+		q, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.punct(")") {
+			return nil, errors.New("missing )")
+		}
+		p.pos++
+		return q, nil
+	}
+
+	tok := p.tokens[p.pos]
+	if !tok.quoted && (tok.text == ")" || p.keyword("and") || p.keyword("or")) {
+		return nil, fmt.Errorf("unexpected %q", tok.text)
+	}
+	p.pos++
+	if tok.quoted {
+		return Text(tok.text), nil
+	}
+	return p.term(tok.text)
+}
+
+// term parses an unquoted word as a Context, Project, Compare, Regexp or
+// Text.
+func (p *queryParser) term(word string) (Query, error) {
+	if len(word) > 1 && word[0] == '@' {
+		return Context(word[1:]), nil
+	}
+	if len(word) > 1 && word[0] == '+' {
+		return Project(word[1:]), nil
+	}
+
+	i := strings.IndexAny(word, "<>=!~")
+	if i <= 0 || !isFieldName(word[:i]) {
+		return Text(word), nil
+	}
+	field, rest := word[:i], word[i:]
+
+	op := Op(rest[:1])
+	if len(rest) > 1 && rest[1] == '=' && rest[0] != '=' && rest[0] != '~' {
+		op = Op(rest[:2])
+	}
+	value := rest[len(op):]
+	if op == "!" || value == "" {
+		// Not a whole comparison, such as "bob!" or "x=".
+		return Text(word), nil
+	}
+
+	if op == "~" {
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", word, err)
+		}
+		return Regexp{Field: field, Regexp: re}, nil
+	}
+
+	switch {
+	case !queryFields[field]:
+		// The word may not be meant as a key:value comparison at all, as
+		// in "x=1", so it matches as text too.
+		return Or{Compare{Field: field, Op: op, Value: value}, Text(word)}, nil
+	case field == "pri":
+		pri, err := ParsePriority(value)
+		if err != nil {
+			return nil, err
+		}
+		value = string(rune(pri))
+	case dateFields[field]:
+		date, err := ParseDate(value, p.now)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", word, err)
+		}
+		value = date.Format(dateLayout)
+	}
+	return Compare{Field: field, Op: op, Value: value}, nil
+}
+
+// isFieldName reports whether s can name a field: letters, digits, "-" and
+// "_".
+func isFieldName(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
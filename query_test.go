@@ -0,0 +1,154 @@
+package todo
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestParseQuery(t *testing.T) {
+	now := time.Date(2026, 5, 23, 15, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		in   string
+		want Query
+	}{
+		{"@work", Context("work")},
+		{"+home", Project("home")},
+		{"milk", Text("milk")},
+		{`"and"`, Text("and")},
+		{`"buy milk"`, Text("buy milk")},
+		{"@work and not +home", And{Context("work"), Not{Project("home")}}},
+		{"@work +home", And{Context("work"), Project("home")}},
+		{"@a or @b and @c", Or{Context("a"), And{Context("b"), Context("c")}}},
+		{"not @a and @b or @c", Or{And{Not{Context("a")}, Context("b")}, Context("c")}},
+		{"(@a OR @b) @c", And{Or{Context("a"), Context("b")}, Context("c")}},
+		{"pri<=b", Compare{Field: "pri", Op: OpLe, Value: "B"}},
+		{"due<today", Compare{Field: "due", Op: OpLt, Value: "2026-05-23"}},
+		{"t>=tomorrow", Compare{Field: "t", Op: OpGe, Value: "2026-05-24"}},
+		{"created>2026-01-01", Compare{Field: "created", Op: OpGt, Value: "2026-01-01"}},
+		{"estimate!=3", Or{Compare{Field: "estimate", Op: OpNe, Value: "3"}, Text("estimate!=3")}},
+		{`"estimate!=3"`, Text("estimate!=3")},
+		{"bob!", Text("bob!")},
+		{"due<", Text("due<")},
+		{"due!x", Text("due!x")},
+		{`text~"^buy (milk|eggs)"`, Regexp{Field: "text", Regexp: regexp.MustCompile("^buy (milk|eggs)")}},
+		{"2+2=4", Text("2+2=4")},
+		{"-@home", Not{Context("home")}},
+		{"@work -+home -milk", And{And{Context("work"), Not{Project("home")}}, Not{Text("milk")}}},
+		{"-(@a or @b)", Not{Or{Context("a"), Context("b")}}},
+		{`"-@home"`, Text("-@home")},
+		{"text~^(a|b) (@c)", And{Regexp{Field: "text", Regexp: regexp.MustCompile("^(a|b)")}, Context("c")}},
+	}
+	for _, tc := range tests {
+		got, err := ParseQuery(tc.in, now)
+		if err != nil {
+			t.Errorf("ParseQuery(%q): %v", tc.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ParseQuery(%q) = %#v, want %#v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestParseQuery_Invalid(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"@a and",
+		"or @a",
+		"(@a",
+		"@a)",
+		`"unclosed`,
+		"pri<=7",
+		"due<soon",
+		"text~(",
+		"-",
+	} {
+		if q, err := ParseQuery(in, time.Now()); err == nil {
+			t.Errorf("ParseQuery(%q) = %#v, want error", in, q)
+		}
+	}
+}
+
+func TestQuery_Match(t *testing.T) {
+	now := time.Date(2026, 5, 23, 9, 0, 0, 0, time.Local)
+	lines := []string{
+		"(A) 2026-01-10 fix bug @work +backend due:2026-05-20",
+		"(C) 2025-12-01 buy milk @home",
+		"2026-03-01 plan the @workshop +backend due:2026-06-01 estimate:12",
+		"x 2026-05-01 2026-02-01 call mum @home estimate:3",
+		"2026-04-01 ask bob! if x=1",
+	}
+	items := make([]Item, len(lines))
+	for i, line := range lines {
+		if err := items[i].UnmarshalText([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []int // indexes into lines
+	}{
+		{"@work", []int{0}},
+		{"@work or @workshop", []int{0, 2}},
+		{"+backend and not @work", []int{2}},
+		{"not @home", []int{0, 2, 4}},
+		{"pri<=B", []int{0}},
+		{"pri>A", []int{1}},
+		{"due<today", []int{0}},
+		{"created>2026-01-01", []int{0, 2, 3, 4}},
+		{"completed>=2026-05-01", []int{3}},
+		{"estimate>5", []int{2}},
+		{"text~^(buy|call)", []int{1, 3}},
+		{"milk", []int{1}},
+		{"bob!", []int{4}},
+		{"x=1", []int{4}},
+		{"estimate=3", []int{3}},
+	}
+	for _, tc := range tests {
+		q, err := ParseQuery(tc.query, now)
+		if err != nil {
+			t.Errorf("ParseQuery(%q): %v", tc.query, err)
+			continue
+		}
+		var got []int
+		for i, item := range items {
+			if q.Match(item) {
+				got = append(got, i)
+			}
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%q matched lines %v, want %v", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestIgnoreCase(t *testing.T) {
+	var item Item
+	if err := item.UnmarshalText([]byte("Buy MILK @Home")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"milk", true},
+		{"not milk", false},
+		{"text~^buy", true},
+		{"@home", false}, // contexts still match exactly
+		{"@Home buy", true},
+	}
+	for _, tc := range tests {
+		q, err := ParseQuery(tc.query, time.Now())
+		if err != nil {
+			t.Fatalf("ParseQuery(%q): %v", tc.query, err)
+		}
+		if got := IgnoreCase(q).Match(item); got != tc.want {
+			t.Errorf("IgnoreCase(%q).Match = %v, want %v", tc.query, got, tc.want)
+		}
+	}
+}
@@ -2,13 +2,17 @@ package todo
 
 import (
 	"bufio"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 )
 
 type Priority rune
@@ -17,9 +21,43 @@ func (p Priority) Valid() bool {
 	return p >= 'A' && p <= 'Z'
 }
 
+// String returns the priority letter, or "" if p is not a valid priority.
+func (p Priority) String() string {
+	if !p.Valid() {
+		return ""
+	}
+	return string(rune(p))
+}
+
+// ParsePriority parses a priority letter from A to Z. Lower case letters are
+// accepted and converted to upper case.
+func ParsePriority(s string) (Priority, error) {
+	if len(s) != 1 {
+		return 0, fmt.Errorf("invalid priority %q: want a letter A-Z", s)
+	}
+	p := Priority(unicode.ToUpper(rune(s[0])))
+	if !p.Valid() {
+		return 0, fmt.Errorf("invalid priority %q: want a letter A-Z", s)
+	}
+	return p, nil
+}
+
+// Id identifies an item within a List. It is the 1-based line number of the
+// item in its todo.txt file, so it survives sorting, filtering and the
+// removal of other items.
 type Id int
 
+var (
+	// ErrNoItem is returned when an Id does not refer to an item in a List.
+	ErrNoItem = errors.New("no such item")
+
+	// ErrConflict is returned by WriteFile when the file has been changed by
+	// another writer since the List was read.
+	ErrConflict = errors.New("file changed since it was read")
+)
+
 type Item struct {
+	ID            Id                `json:"id"` // Assigned by List; not part of the todo.txt line.
 	Message       string            `json:"description"`
 	Done          bool              `json:"done"`
 	Priority      Priority          `json:"priority"`
@@ -27,9 +65,11 @@ type Item struct {
 	CompletedDate time.Time         `json:"completed-date"`
 	Projects      []string          `json:"projects"`
 	Contexts      []string          `json:"contexts"`
-	SpecialKeys   map[string]string `json:"special-keys"` // Not used, but should be preserved for other tools.
+	SpecialKeys   map[string]string `json:"special-keys"` // See Due, Threshold and Recurrence; others are kept for other tools.
 }
 
+// MarshalText returns the item as a todo.txt line. Text read by
+// UnmarshalText is reproduced byte for byte.
 func (i *Item) MarshalText() (text []byte, err error) {
 	var parts []string
 
@@ -41,11 +81,11 @@ func (i *Item) MarshalText() (text []byte, err error) {
 		parts = append(parts, fmt.Sprintf("(%c)", rune(i.Priority)))
 	}
 
+	if i.Done && !i.CompletedDate.IsZero() {
+		parts = append(parts, i.CompletedDate.Format(dateLayout))
+	}
 	if !i.CreatedDate.IsZero() {
-		if !i.CompletedDate.IsZero() {
-			parts = append(parts, i.CompletedDate.Format("2006-01-02"))
-		}
-		parts = append(parts, i.CreatedDate.Format("2006-01-02"))
+		parts = append(parts, i.CreatedDate.Format(dateLayout))
 	}
 
 	parts = append(parts, i.Message)
@@ -53,113 +93,283 @@ func (i *Item) MarshalText() (text []byte, err error) {
 	return []byte(strings.Join(parts, " ")), nil
 }
 
+// UnmarshalText parses a todo.txt line into the item, following the format
+// at https://github.com/todotxt/todo.txt. Every field but the Id is reset.
+//
+// A line is complete if it starts with "x " and has a priority if it then
+// starts with "(A) " for a letter A-Z; priorities are kept on complete
+// items, which the spec leaves undefined. A complete item's first date is
+// its completion date and any second date its creation date; an open item
+// has only a creation date. Each of these prefixes must be followed by a
+// space, and anything not matching them is part of the message.
 func (i *Item) UnmarshalText(text []byte) error {
-	textString := string(text)
-	if len(textString) == 0 {
+	rest := string(text)
+	if len(rest) == 0 {
 		return errors.New("no item found in text")
 	}
 
-	nextPosition := 0
+	*i = Item{ID: i.ID}
 
-	// lowercase x indicates item is done
-	if textString[nextPosition] == 'x' {
+	if strings.HasPrefix(rest, "x ") {
 		i.Done = true
-		nextPosition += 2
-		if nextPosition > len(textString) {
-			nextPosition = len(textString)
-		}
+		rest = rest[2:]
 	}
 
-	// optional priority: "(A) " — requires at least 2 bytes at nextPosition to read the letter
-	if nextPosition+1 < len(textString) && textString[nextPosition] == '(' {
-		i.Priority = Priority(text[nextPosition+1])
-		nextPosition += 4 // skip "(A) "
-		if nextPosition > len(textString) {
-			nextPosition = len(textString)
-		}
+	if len(rest) >= 4 && rest[0] == '(' && rest[2] == ')' && rest[3] == ' ' && Priority(rest[1]).Valid() {
+		i.Priority = Priority(rest[1])
+		rest = rest[4:]
 	}
 
-	// optional completion and/or creation date
-	if nextPosition+10 <= len(textString) {
-		if firstDate, err := time.ParseInLocation("2006-01-02", textString[nextPosition:nextPosition+10], time.Local); err == nil {
-			nextPosition += 11
-
-			if nextPosition+10 <= len(textString) {
-				if createdDate, err := time.ParseInLocation("2006-01-02", textString[nextPosition:nextPosition+10], time.Local); err == nil {
-					i.CreatedDate = createdDate
-					i.CompletedDate = firstDate
-					nextPosition += 11
-				} else {
-					i.CreatedDate = firstDate
-				}
-			} else {
-				i.CreatedDate = firstDate
+	if date, after, ok := cutDate(rest); ok {
+		rest = after
+		if !i.Done {
+			i.CreatedDate = date
+		} else {
+			i.CompletedDate = date
+			if created, after, ok := cutDate(rest); ok {
+				i.CreatedDate = created
+				rest = after
 			}
 		}
 	}
 
-	i.Message = textString[nextPosition:]
-	i.Projects, i.Contexts, i.SpecialKeys = parseMessage(textString[nextPosition:])
-
+	i.SetMessage(rest)
 	return nil
 }
 
+// dateLayout is the todo.txt date format.
+const dateLayout = "2006-01-02"
+
+// cutDate parses a date followed by a space from the start of s, returning
+// the date and the text after the space.
+func cutDate(s string) (date time.Time, rest string, ok bool) {
+	if len(s) < len(dateLayout)+1 || s[len(dateLayout)] != ' ' {
+		return time.Time{}, s, false
+	}
+	date, err := time.ParseInLocation(dateLayout, s[:len(dateLayout)], time.Local)
+	if err != nil {
+		return time.Time{}, s, false
+	}
+	return date, s[len(dateLayout)+1:], true
+}
+
+// SetMessage replaces the item's message and re-derives its projects,
+// contexts and special keys from the new text.
+func (i *Item) SetMessage(message string) {
+	i.Message = message
+	i.Projects, i.Contexts, i.SpecialKeys = parseMessage(message)
+}
+
+// parseMessage extracts the +projects, @contexts and key:value pairs from
+// message. A key:value pair needs a non-empty key and value and no further
+// colon, and values starting with "//" are taken to be URLs, not pairs.
 func parseMessage(message string) (projects []string, contexts []string, specialKeys map[string]string) {
 	for _, word := range strings.Fields(message) {
-		if word[0] == '+' {
+		if len(word) > 1 && word[0] == '+' {
 			projects = append(projects, word[1:])
 			continue
 		}
 
-		if word[0] == '@' {
+		if len(word) > 1 && word[0] == '@' {
 			contexts = append(contexts, word[1:])
 			continue
 		}
 
-		if pos := strings.Index(word, ":"); pos != -1 {
+		if key, value, ok := cutPair(word); ok {
 			if specialKeys == nil {
 				specialKeys = make(map[string]string)
 			}
-			specialKeys[word[:pos]] = word[pos+1:]
+			specialKeys[key] = value
 		}
 	}
 	return projects, contexts, specialKeys
 }
 
+// cutPair splits a word of a message into a key:value pair, reporting
+// whether it is one.
+func cutPair(word string) (key, value string, ok bool) {
+	key, value, ok = strings.Cut(word, ":")
+	ok = ok && key != "" && value != "" && !strings.Contains(value, ":") && !strings.HasPrefix(value, "//")
+	return key, value, ok
+}
+
+// itemList holds items in ascending Id order.
 type itemList []*Item
 
 // List is a list of todo items. List is safe for concurrent use.
+//
+// A List read from a file remembers the file's lines, so WriteFile can put
+// back blank lines, lines that are not items and items that have not been
+// changed exactly as they were read.
 type List struct {
 	sync.RWMutex
 	list itemList
+
+	raw            map[Id]string // original text by line number, without "\n"
+	end            Id            // last line number in use, by the file or an Add
+	newline        string        // line ending for lines not in raw: "\n" or "\r\n"
+	noFinalNewline bool          // the file did not end with a line ending
+	unterminated   Id            // the file's last line if it had no line ending, else 0
+
+	source *fileState // the file as last read or written, for conflict checks
 }
 
+// fileState identifies the content of a file at the time it was read or
+// written, so WriteFile can tell whether another writer has changed it.
+type fileState struct {
+	path   string
+	exists bool
+	sum    [sha256.Size]byte
+}
+
+// readState returns the current state of the file at path.
+func readState(path string) (*fileState, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path is the caller's todo file.
+	if os.IsNotExist(err) {
+		return &fileState{path: path}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &fileState{path: path, exists: true, sum: sha256.Sum256(data)}, nil
+}
+
+// index returns the position of the item with the given id in l.list.
+// The caller must hold the lock.
+func (l *List) index(id Id) (int, bool) {
+	idx := sort.Search(len(l.list), func(i int) bool { return l.list[i].ID >= id })
+	return idx, idx < len(l.list) && l.list[idx].ID == id
+}
+
+// lastLine returns the highest line number holding an item or raw text.
+// The caller must hold the lock.
+func (l *List) lastLine() Id {
+	last := Id(0)
+	if n := len(l.list); n > 0 {
+		last = l.list[n-1].ID
+	}
+	for id := range l.raw {
+		last = max(last, id)
+	}
+	return last
+}
+
+// Add appends item to the list, assigning it the next free Id.
 func (l *List) Add(item Item) Item {
 	l.Lock()
 	defer l.Unlock()
+	return l.add(item)
+}
 
+// add appends item to the list. The caller must hold the lock.
+func (l *List) add(item Item) Item {
+	l.end++
+	item.ID = l.end
 	l.list = append(l.list, &item)
 	return item
 }
 
-func (l *List) Remove(id Id) {
+// Remove deletes the item with the given id. The Ids of other items are
+// unchanged.
+func (l *List) Remove(id Id) error {
 	l.Lock()
 	defer l.Unlock()
 
-	l.list = append(l.list[:id], l.list[id+1:]...)
+	idx, ok := l.index(id)
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrNoItem, id)
+	}
+	l.list = append(l.list[:idx], l.list[idx+1:]...)
+	delete(l.raw, id)
+	return nil
 }
 
 func (l *List) Get(id Id) (Item, bool) {
 	l.RLock()
 	defer l.RUnlock()
 
-	idx := int(id)
-	if idx < 0 || idx >= len(l.list) {
+	idx, ok := l.index(id)
+	if !ok {
 		return Item{}, false
 	}
 	return *l.list[idx], true
 }
 
+// Set replaces the item with the given id by item, keeping its Id.
+func (l *List) Set(id Id, item Item) error {
+	l.Lock()
+	defer l.Unlock()
+
+	idx, ok := l.index(id)
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrNoItem, id)
+	}
+	item.ID = id
+	l.list[idx] = &item
+	delete(l.raw, id)
+	return nil
+}
+
+// Complete marks the item with the given id as done, completed on date. If
+// the item was open and has a rec: key, its next occurrence, as given by
+// Item.Next, is added to the end of the list and next is its Id; otherwise
+// next is 0.
+func (l *List) Complete(id Id, date time.Time) (next Id, err error) {
+	l.Lock()
+	defer l.Unlock()
+
+	idx, ok := l.index(id)
+	if !ok {
+		return 0, fmt.Errorf("%w: %d", ErrNoItem, id)
+	}
+	item := l.list[idx]
+	wasOpen := !item.Done
+	item.Done = true
+	item.CompletedDate = date
+	delete(l.raw, id)
+
+	if !wasOpen {
+		return 0, nil
+	}
+	occurrence, ok := item.Next(date)
+	if !ok {
+		return 0, nil
+	}
+	return l.add(occurrence).ID, nil
+}
+
+// Compact renumbers the items so their Ids run consecutively from 1, closing
+// the gaps left by Remove and dropping blank lines. Lines that are not items
+// are kept in place. Ids held by callers are invalid afterwards.
+func (l *List) Compact() {
+	l.Lock()
+	defer l.Unlock()
+
+	raw := make(map[Id]string, len(l.raw))
+	next, idx := Id(1), 0
+	unterminated := Id(0)
+	last := l.lastLine()
+	for line := Id(1); line <= last; line++ {
+		text, hasRaw := l.raw[line]
+		if idx < len(l.list) && l.list[idx].ID == line {
+			l.list[idx].ID = next
+			idx++
+		} else if !hasRaw || strings.TrimSpace(text) == "" {
+			continue
+		}
+		if hasRaw {
+			raw[next] = text
+		}
+		if line == l.unterminated {
+			unterminated = next
+		}
+		next++
+	}
+	l.raw = raw
+	l.unterminated = unterminated
+	l.end = next - 1
+}
+
 func (l *List) GetAll() []Item {
 	l.RLock()
 	defer l.RUnlock()
@@ -171,23 +381,40 @@ func (l *List) GetAll() []Item {
 	return items
 }
 
-// ReadFile reads a todo.txt file and returns a List.
+// ReadFile reads a todo.txt file and returns a List. Each item's Id is the
+// line it was read from. Blank lines and lines that do not parse as items
+// are kept for WriteFile but not returned as items.
 // Returns an empty list if the file does not exist.
 func ReadFile(path string) (*List, error) {
 	path = filepath.Clean(path)
-	f, err := os.Open(path)
+	list := &List{list: make(itemList, 0), raw: make(map[Id]string), source: &fileState{path: path}}
+
+	data, err := os.ReadFile(path)
 	if os.IsNotExist(err) {
-		return &List{list: make(itemList, 0)}, nil
+		return list, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	defer func() { _ = f.Close() }()
+	list.source = &fileState{path: path, exists: true, sum: sha256.Sum256(data)}
+	if len(data) == 0 {
+		return list, nil
+	}
 
-	list := &List{list: make(itemList, 0)}
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+	text := string(data)
+	list.noFinalNewline = !strings.HasSuffix(text, "\n")
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if list.noFinalNewline {
+		list.unterminated = Id(len(lines))
+	}
+	if strings.HasSuffix(lines[0], "\r") {
+		list.newline = "\r\n"
+	}
+
+	for n, raw := range lines {
+		id := Id(n + 1)
+		list.raw[id] = raw
+		line := strings.TrimSpace(raw)
 		if line == "" {
 			continue
 		}
@@ -195,33 +422,112 @@ func ReadFile(path string) (*List, error) {
 		if err := item.UnmarshalText([]byte(line)); err != nil {
 			continue
 		}
+		item.ID = id
 		list.list = append(list.list, &item)
 	}
-	return list, scanner.Err()
+	list.end = Id(len(lines))
+	return list, nil
+}
+
+// CheckFile returns an error wrapping ErrConflict if list was read from or
+// last written to path and the file has changed since. It is the check
+// WriteFile makes, for callers that need it without writing list.
+func CheckFile(path string, list *List) error {
+	path = filepath.Clean(path)
+
+	list.RLock()
+	source := list.source
+	list.RUnlock()
+	if source == nil || source.path != path {
+		return nil
+	}
+	current, err := readState(path)
+	if err != nil {
+		return err
+	}
+	if *current != *source {
+		return fmt.Errorf("%w: %s", ErrConflict, path)
+	}
+	return nil
 }
 
 // WriteFile writes all items in the list to path in todo.txt format.
-// The write is atomic: a temp file is written then renamed into place.
+// Each item is written on the line given by its Id, with blank lines filling
+// any gaps, so Ids are unchanged when the file is read back. Lines kept by
+// ReadFile are written back as they were read, using the file's original
+// line endings; only new and changed items are formatted with MarshalText.
+// The write is atomic and durable: a uniquely named temp file is written and
+// synced, renamed into place, and then the directory is synced. If path is a
+// symlink the file it points to is replaced, and an existing file's
+// permissions and, where possible, ownership are kept.
+//
+// If list was read from or last written to path, WriteFile first checks that
+// the file has not changed since, and returns an error wrapping ErrConflict
+// without writing if it has. Hold a LockFile lock from before reading until
+// after writing to make the check and the write atomic.
 func WriteFile(path string, list *List) error {
-	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+	path = filepath.Clean(path)
+	if err := CheckFile(path, list); err != nil {
 		return err
 	}
 
-	tmpPath := filepath.Clean(path + ".tmp")
-	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	// Write to the real file behind any symlinks, so the links survive.
+	target, err := resolveSymlinks(path)
 	if err != nil {
 		return err
 	}
+	dir := filepath.Dir(target)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+	existing, err := os.Stat(target)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// A unique temp name keeps concurrent writers from sharing a file.
+	f, err := os.CreateTemp(dir, "."+filepath.Base(target)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := f.Name()
 
 	list.RLock()
-	w := bufio.NewWriter(f)
+	hash := sha256.New()
+	w := bufio.NewWriter(io.MultiWriter(f, hash))
 	writeErr := func() error {
-		for _, item := range list.list {
-			text, err := item.MarshalText()
-			if err != nil {
-				return err
+		newline := list.newline
+		if newline == "" {
+			newline = "\n"
+		}
+
+		last, idx := list.lastLine(), 0
+		for line := Id(1); line <= last; line++ {
+			text, hasRaw := list.raw[line]
+			ending := "\n"
+			if idx < len(list.list) && list.list[idx].ID == line {
+				item := list.list[idx]
+				idx++
+				if !hasRaw {
+					b, err := item.MarshalText()
+					if err != nil {
+						return err
+					}
+					text, ending = string(b), newline
+				}
+			} else if !hasRaw {
+				ending = newline
+			}
+
+			// The raw text of a line read without a line ending has no
+			// "\r" either, so it needs the file's whole ending.
+			if line == list.unterminated {
+				ending = newline
+			}
+			if line == last && list.noFinalNewline {
+				ending = ""
 			}
-			if _, err := fmt.Fprintf(w, "%s\n", text); err != nil {
+			if _, err := w.WriteString(text + ending); err != nil {
 				return err
 			}
 		}
@@ -229,12 +535,133 @@ func WriteFile(path string, list *List) error {
 	}()
 	list.RUnlock()
 
+	if writeErr == nil && existing != nil {
+		writeErr = preserveMode(f, existing)
+	}
+	if writeErr == nil {
+		writeErr = f.Sync()
+	}
 	if closeErr := f.Close(); closeErr != nil && writeErr == nil {
 		writeErr = closeErr
 	}
+	if writeErr == nil {
+		writeErr = os.Rename(tmpPath, target)
+	}
 	if writeErr != nil {
 		_ = os.Remove(tmpPath)
 		return writeErr
 	}
-	return os.Rename(tmpPath, path)
+	if err := syncDir(dir); err != nil {
+		return err
+	}
+
+	written := &fileState{path: path, exists: true}
+	hash.Sum(written.sum[:0])
+	list.Lock()
+	list.source = written
+	list.Unlock()
+	return nil
+}
+
+// AppendFile appends items to the todo.txt file at path, creating it if
+// needed. Existing content is left untouched, the items take the file's line
+// ending as ReadFile detects it, and the file is synced before AppendFile
+// returns so the items are on disk.
+func AppendFile(path string, items []Item) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	path = filepath.Clean(path)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0o600)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	writeErr := func() error {
+		stat, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		newline := "\n"
+		if size := stat.Size(); size > 0 {
+			// Use the ending of the first line, as ReadFile does.
+			first, err := bufio.NewReader(io.NewSectionReader(f, 0, size)).ReadString('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				return err
+			}
+			if strings.HasSuffix(strings.TrimSuffix(first, "\n"), "\r") {
+				newline = "\r\n"
+			}
+
+			// Start on a fresh line if the file does not end with a newline.
+			last := make([]byte, 1)
+			if _, err := f.ReadAt(last, size-1); err != nil {
+				return err
+			}
+			if last[0] != '\n' {
+				if _, err := w.WriteString(newline); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, item := range items {
+			text, err := item.MarshalText()
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "%s%s", text, newline); err != nil {
+				return err
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		return f.Sync()
+	}()
+
+	if closeErr := f.Close(); closeErr != nil && writeErr == nil {
+		writeErr = closeErr
+	}
+	return writeErr
+}
+
+// maxSymlinks bounds symlink resolution, as the kernel does, to stop loops.
+const maxSymlinks = 40
+
+// resolveSymlinks follows path through any symlinks to the file they name,
+// which need not exist yet.
+func resolveSymlinks(path string) (string, error) {
+	for range maxSymlinks {
+		fi, err := os.Lstat(path)
+		if os.IsNotExist(err) {
+			return path, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if fi.Mode()&os.ModeSymlink == 0 {
+			return path, nil
+		}
+		link, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(link) {
+			link = filepath.Join(filepath.Dir(path), link)
+		}
+		path = link
+	}
+	return "", fmt.Errorf("too many levels of symbolic links: %s", path)
+}
+
+// preserveMode gives f the permissions and, if allowed, the ownership of the
+// existing file it is replacing.
+func preserveMode(f *os.File, existing os.FileInfo) error {
+	if err := f.Chmod(existing.Mode().Perm()); err != nil {
+		return err
+	}
+	return preserveOwner(f, existing)
 }
@@ -3,9 +3,11 @@ package todo
 // TODO: This would be a good candidate for fuzz testing
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"reflect"
+	"runtime"
 	"testing"
 	"time"
 )
@@ -51,15 +53,15 @@ func TestItem_UnmarshalText(t *testing.T) {
 			},
 		},
 		{
-			Name:  "Complete description creation date",
+			Name:  "Complete description completed date",
 			Item:  "x 2024-05-18 Complete this test",
 			Valid: true,
 			Expected: Item{
 				Message:       "Complete this test",
 				Done:          true,
 				Priority:      0,
-				CreatedDate:   time.Date(2024, 05, 18, 0, 0, 0, 0, time.Local),
-				CompletedDate: time.Time{},
+				CreatedDate:   time.Time{},
+				CompletedDate: time.Date(2024, 05, 18, 0, 0, 0, 0, time.Local),
 				Projects:      nil,
 				Contexts:      nil,
 				SpecialKeys:   nil,
@@ -157,6 +159,133 @@ func TestItem_UnmarshalText(t *testing.T) {
 	}
 }
 
+// TestItem_UnmarshalText_Spec checks the examples from the todo.txt format
+// description at https://github.com/todotxt/todo.txt.
+func TestItem_UnmarshalText_Spec(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		line string
+		want Item
+	}{
+		// Rule 1: priority comes first, as an uppercase letter in parentheses followed by a space.
+		{"(A) Call Mom", Item{Priority: 'A', Message: "Call Mom"}},
+		{"Really gotta call Mom (A) @phone @someday", Item{
+			Message:  "Really gotta call Mom (A) @phone @someday",
+			Contexts: []string{"phone", "someday"},
+		}},
+		{"(b) Get back to the boss", Item{Message: "(b) Get back to the boss"}},
+		{"(B)->Submit TPS report", Item{Message: "(B)->Submit TPS report"}},
+		// Rule 2: a creation date may follow the priority and a space.
+		{"2011-03-02 Document +TodoTxt task format", Item{
+			CreatedDate: date(2011, 3, 2),
+			Message:     "Document +TodoTxt task format",
+			Projects:    []string{"TodoTxt"},
+		}},
+		{"(A) 2011-03-02 Call Mom", Item{Priority: 'A', CreatedDate: date(2011, 3, 2), Message: "Call Mom"}},
+		{"(A) Call Mom 2011-03-02", Item{Priority: 'A', Message: "Call Mom 2011-03-02"}},
+		// Rule 3: contexts and projects may appear anywhere after priority and date.
+		{"(A) Call Mom +Family +PeaceLoveAndHappiness @iphone @phone", Item{
+			Priority: 'A',
+			Message:  "Call Mom +Family +PeaceLoveAndHappiness @iphone @phone",
+			Projects: []string{"Family", "PeaceLoveAndHappiness"},
+			Contexts: []string{"iphone", "phone"},
+		}},
+		{"Email SoAndSo at soandso@example.com", Item{Message: "Email SoAndSo at soandso@example.com"}},
+		{"Learn how to add 2+2", Item{Message: "Learn how to add 2+2"}},
+		// Complete tasks rule 1: a complete task starts with a lowercase x and a space.
+		{"x 2011-03-03 Call Mom", Item{Done: true, CompletedDate: date(2011, 3, 3), Message: "Call Mom"}},
+		{"xylophone lesson", Item{Message: "xylophone lesson"}},
+		{"X 2012-01-01 Make resolutions", Item{Message: "X 2012-01-01 Make resolutions"}},
+		{"(A) x Find ticket prices", Item{Priority: 'A', Message: "x Find ticket prices"}},
+		// Complete tasks rule 2: the completion date comes right after the x, then the creation date.
+		{"x 2011-03-02 2011-03-01 Review Tim's pull request +TodoTxtTouch @github", Item{
+			Done:          true,
+			CompletedDate: date(2011, 3, 2),
+			CreatedDate:   date(2011, 3, 1),
+			Message:       "Review Tim's pull request +TodoTxtTouch @github",
+			Projects:      []string{"TodoTxtTouch"},
+			Contexts:      []string{"github"},
+		}},
+		// Additional file format definitions: key:value pairs.
+		{"Pay rent due:2011-04-01", Item{Message: "Pay rent due:2011-04-01", SpecialKeys: map[string]string{"due": "2011-04-01"}}},
+
+		// Edge cases beyond the spec's own examples.
+		{"x (A) 2011-03-02 2011-03-01 Keep priority", Item{
+			Done:          true,
+			Priority:      'A',
+			CompletedDate: date(2011, 3, 2),
+			CreatedDate:   date(2011, 3, 1),
+			Message:       "Keep priority",
+		}},
+		{"2011-03-02 2011-03-01 Open items have one date", Item{CreatedDate: date(2011, 3, 2), Message: "2011-03-01 Open items have one date"}},
+		{"(A)Missing space", Item{Message: "(A)Missing space"}},
+		{"(A] Wrong bracket", Item{Message: "(A] Wrong bracket"}},
+		{"2011-02-30 Not a date", Item{Message: "2011-02-30 Not a date"}},
+		{"2011-03-02Missing space", Item{Message: "2011-03-02Missing space"}},
+		{"x", Item{Message: "x"}},
+		{"Lone + and @ are text, as are :key, key: and http://example.com", Item{
+			Message: "Lone + and @ are text, as are :key, key: and http://example.com",
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.line, func(t *testing.T) {
+			var got Item
+			if err := got.UnmarshalText([]byte(tc.line)); err != nil {
+				t.Fatalf("UnmarshalText: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %+v\nbut expected: %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestItem_RoundTrip checks that valid lines survive UnmarshalText and
+// MarshalText byte for byte.
+func TestItem_RoundTrip(t *testing.T) {
+	lines := []string{
+		"(A) Call Mom",
+		"Really gotta call Mom (A) @phone @someday",
+		"(b) Get back to the boss",
+		"(B)->Submit TPS report",
+		"2011-03-02 Document +TodoTxt task format",
+		"(A) 2011-03-02 Call Mom",
+		"(A) Call Mom 2011-03-02",
+		"x 2011-03-03 Call Mom",
+		"xylophone lesson",
+		"X 2012-01-01 Make resolutions",
+		"(A) x Find ticket prices",
+		"x 2011-03-02 2011-03-01 Review Tim's pull request +TodoTxtTouch @github",
+		"x (A) 2011-03-02 2011-03-01 Keep priority",
+		"2011-03-02 2011-03-01 Two dates on an open item",
+		"x 2011-03-02 2011-03-01 2011-02-28 Three dates",
+		"(A)  Double  spaces  ",
+		"(A) ",
+		"2011-03-02 ",
+		"x ",
+		"x",
+	}
+	for _, line := range lines {
+		var item Item
+		if err := item.UnmarshalText([]byte(line)); err != nil {
+			t.Errorf("UnmarshalText(%q): %v", line, err)
+			continue
+		}
+		text, err := item.MarshalText()
+		if err != nil {
+			t.Errorf("MarshalText(%q): %v", line, err)
+			continue
+		}
+		if string(text) != line {
+			t.Errorf("round trip of %q gave %q", line, text)
+		}
+	}
+}
+
 func TestItem_MarshalText(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -210,6 +339,24 @@ func TestItem_MarshalText(t *testing.T) {
 			},
 			expected: "2024-01-15 buy milk +groceries @errands",
 		},
+		{
+			name: "done with completed date but no creation date",
+			item: Item{
+				Message:       "buy milk",
+				Done:          true,
+				CompletedDate: time.Date(2024, 1, 15, 0, 0, 0, 0, time.Local),
+			},
+			expected: "x 2024-01-15 buy milk",
+		},
+		{
+			name: "open item ignores completed date",
+			item: Item{
+				Message:       "buy milk",
+				CreatedDate:   time.Date(2024, 1, 14, 0, 0, 0, 0, time.Local),
+				CompletedDate: time.Date(2024, 1, 15, 0, 0, 0, 0, time.Local),
+			},
+			expected: "2024-01-14 buy milk",
+		},
 		{
 			name: "with priority",
 			item: Item{
@@ -307,7 +454,7 @@ func TestReadWriteFile(t *testing.T) {
 	}
 }
 
-// TestWriteFile_Atomic checks that WriteFile does not leave a .tmp file on success.
+// TestWriteFile_Atomic checks that WriteFile does not leave a temp file on success.
 func TestWriteFile_Atomic(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "todo.txt")
@@ -317,7 +464,497 @@ func TestWriteFile_Atomic(t *testing.T) {
 	if err := WriteFile(path, list); err != nil {
 		t.Fatalf("WriteFile: %v", err)
 	}
-	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
-		t.Error(".tmp file should not exist after successful write")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "todo.txt" {
+		t.Errorf("directory holds %v, want only todo.txt", entries)
+	}
+}
+
+func TestWriteFile_PreservesMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permissions")
+	}
+	path := filepath.Join(t.TempDir(), "todo.txt")
+	if err := os.WriteFile(path, []byte("first\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0o640); err != nil { // undo the umask
+		t.Fatal(err)
+	}
+
+	list, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	list.Add(Item{Message: "second"})
+	if err := WriteFile(path, list); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stat.Mode().Perm(); got != 0o640 {
+		t.Errorf("mode = %v, want 0640", got)
+	}
+}
+
+// TestWriteFile_Symlink checks that writing through a symlink updates the
+// file it points to and leaves the link in place.
+func TestWriteFile_Symlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "dotfiles", "todo.txt")
+	if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "todo.txt")
+	if err := os.Symlink(filepath.Join("dotfiles", "todo.txt"), link); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	list, err := ReadFile(link)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	list.Add(Item{Message: "second"})
+	if err := WriteFile(link, list); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	fi, err := os.Lstat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&os.ModeSymlink == 0 {
+		t.Error("todo.txt was replaced by a regular file")
+	}
+	data, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "first\nsecond\n" {
+		t.Errorf("target = %q, want both items", data)
+	}
+}
+
+func TestList_Complete(t *testing.T) {
+	list := &List{list: make(itemList, 0)}
+	list.Add(Item{Message: "first"})
+	list.Add(Item{Message: "second"})
+
+	date := time.Date(2024, 1, 15, 0, 0, 0, 0, time.Local)
+	if next, err := list.Complete(2, date); err != nil || next != 0 {
+		t.Fatalf("Complete = %d, %v; want 0, nil", next, err)
+	}
+	item, _ := list.Get(2)
+	if !item.Done || !item.CompletedDate.Equal(date) {
+		t.Errorf("got %+v, want done on %v", item, date)
+	}
+	if first, _ := list.Get(1); first.Done {
+		t.Error("Complete changed the wrong item")
+	}
+
+	for _, id := range []Id{0, 3} {
+		if _, err := list.Complete(id, date); !errors.Is(err, ErrNoItem) {
+			t.Errorf("Complete(%d) error = %v, want ErrNoItem", id, err)
+		}
+	}
+}
+
+func TestList_CompleteRecurring(t *testing.T) {
+	list := &List{list: make(itemList, 0)}
+	list.Add(Item{Message: "first"})
+	var recurring Item
+	recurring.SetMessage("water plants due:2024-01-10 rec:1w")
+	list.Add(recurring)
+
+	date := time.Date(2024, 1, 15, 0, 0, 0, 0, time.Local)
+	next, err := list.Complete(2, date)
+	if err != nil || next != 3 {
+		t.Fatalf("Complete = %d, %v; want 3, nil", next, err)
+	}
+	if item, _ := list.Get(2); !item.Done {
+		t.Errorf("completed item = %+v, want done", item)
+	}
+	item, ok := list.Get(next)
+	if !ok || item.Done || item.Message != "water plants due:2024-01-22 rec:1w" || !item.CreatedDate.Equal(date) {
+		t.Errorf("next occurrence = %+v", item)
+	}
+
+	// Completing it again adds nothing more.
+	if next, err := list.Complete(2, date); err != nil || next != 0 {
+		t.Errorf("Complete again = %d, %v; want 0, nil", next, err)
+	}
+	if n := len(list.GetAll()); n != 3 {
+		t.Errorf("list has %d items, want 3", n)
+	}
+}
+
+// TestList_StableIds checks that Ids follow file line numbers and are not
+// renumbered by Remove or by a write and re-read.
+func TestList_StableIds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todo.txt")
+	if err := os.WriteFile(path, []byte("first\n\nsecond\nthird\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	wantIds := map[string]Id{"first": 1, "second": 3, "third": 4}
+	for _, item := range list.GetAll() {
+		if item.ID != wantIds[item.Message] {
+			t.Errorf("%q has id %d, want %d", item.Message, item.ID, wantIds[item.Message])
+		}
+	}
+
+	if err := list.Remove(3); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if err := list.Remove(3); !errors.Is(err, ErrNoItem) {
+		t.Errorf("second Remove(3) error = %v, want ErrNoItem", err)
+	}
+	if added := list.Add(Item{Message: "fourth"}); added.ID != 5 {
+		t.Errorf("added item has id %d, want 5", added.ID)
+	}
+	if item, ok := list.Get(4); !ok || item.Message != "third" {
+		t.Errorf("Get(4) = %+v, %v; want third", item, ok)
+	}
+
+	if err := WriteFile(path, list); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	got, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile after write: %v", err)
+	}
+	wantIds = map[string]Id{"first": 1, "third": 4, "fourth": 5}
+	items := got.GetAll()
+	if len(items) != len(wantIds) {
+		t.Fatalf("got %d items, want %d", len(items), len(wantIds))
+	}
+	for _, item := range items {
+		if item.ID != wantIds[item.Message] {
+			t.Errorf("after re-read %q has id %d, want %d", item.Message, item.ID, wantIds[item.Message])
+		}
+	}
+}
+
+func TestList_Set(t *testing.T) {
+	list := &List{list: make(itemList, 0)}
+	list.Add(Item{Message: "first"})
+	list.Add(Item{Message: "second"})
+
+	if err := list.Set(2, Item{ID: 7, Message: "replaced"}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	item, ok := list.Get(2)
+	if !ok || item.Message != "replaced" || item.ID != 2 {
+		t.Errorf("Get(2) = %+v, %v; want replaced with id 2", item, ok)
+	}
+	if err := list.Set(3, Item{Message: "missing"}); !errors.Is(err, ErrNoItem) {
+		t.Errorf("Set(3) error = %v, want ErrNoItem", err)
+	}
+}
+
+func TestList_Compact(t *testing.T) {
+	list := &List{list: make(itemList, 0)}
+	for _, msg := range []string{"first", "second", "third"} {
+		list.Add(Item{Message: msg})
+	}
+	if err := list.Remove(2); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	list.Compact()
+	items := list.GetAll()
+	if len(items) != 2 || items[0].ID != 1 || items[1].ID != 2 || items[1].Message != "third" {
+		t.Errorf("after Compact got %+v, want first and third as 1 and 2", items)
+	}
+}
+
+func TestAppendFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		want     string
+	}{
+		// An existing file without a trailing newline must not have the
+		// first appended item joined onto its last line.
+		{"no final newline", "x old item", "x old item\nx first\nx second\n"},
+		{"new file", "", "x first\nx second\n"},
+		{"CRLF", "x old item\r\n", "x old item\r\nx first\r\nx second\r\n"},
+		{"CRLF without final newline", "x old\r\nx item", "x old\r\nx item\r\nx first\r\nx second\r\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "done.txt")
+			if tc.existing != "" {
+				if err := os.WriteFile(path, []byte(tc.existing), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			items := []Item{{Message: "first", Done: true}, {Message: "second", Done: true}}
+			if err := AppendFile(path, items); err != nil {
+				t.Fatalf("AppendFile: %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tc.want {
+				t.Errorf("file = %q, want %q", data, tc.want)
+			}
+		})
+	}
+}
+
+func TestItem_SetMessage(t *testing.T) {
+	item := Item{Message: "old +gone @gone", Projects: []string{"gone"}, Contexts: []string{"gone"}}
+	item.SetMessage("new +proj @ctx due:2024-01-01")
+
+	want := Item{
+		Message:     "new +proj @ctx due:2024-01-01",
+		Projects:    []string{"proj"},
+		Contexts:    []string{"ctx"},
+		SpecialKeys: map[string]string{"due": "2024-01-01"},
+	}
+	if !reflect.DeepEqual(item, want) {
+		t.Errorf("got %+v, want %+v", item, want)
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  Priority
+		valid bool
+	}{
+		{"A", 'A', true},
+		{"z", 'Z', true},
+		{"", 0, false},
+		{"AB", 0, false},
+		{"1", 0, false},
+		{"(", 0, false},
+	}
+	for _, tc := range tests {
+		got, err := ParsePriority(tc.in)
+		if (err == nil) != tc.valid || got != tc.want {
+			t.Errorf("ParsePriority(%q) = %q, %v; want %q, valid %v", tc.in, got, err, tc.want, tc.valid)
+		}
+	}
+}
+
+func TestPriority_String(t *testing.T) {
+	if got := Priority('B').String(); got != "B" {
+		t.Errorf("String() = %q, want B", got)
+	}
+	if got := Priority(0).String(); got != "" {
+		t.Errorf("String() of no priority = %q, want empty", got)
+	}
+}
+
+// TestReadWriteFile_Lossless checks that lines WriteFile did not need to
+// change are written back exactly as ReadFile found them.
+func TestReadWriteFile_Lossless(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		edit    func(t *testing.T, list *List)
+		want    string
+	}{
+		{
+			name:    "unchanged",
+			content: "(A)  spaced   item  \n\n   \n# a comment\nx  not a completion\n",
+			edit:    func(*testing.T, *List) {},
+			want:    "(A)  spaced   item  \n\n   \n# a comment\nx  not a completion\n",
+		},
+		{
+			name:    "add keeps existing lines",
+			content: "first  \n\n# notes\n",
+			edit: func(_ *testing.T, list *List) {
+				list.Add(Item{Message: "added"})
+			},
+			want: "first  \n\n# notes\nadded\n",
+		},
+		{
+			name:    "changed item is reformatted, others untouched",
+			content: "  first\n  second\n  third\n",
+			edit: func(t *testing.T, list *List) {
+				if _, err := list.Complete(2, time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: "  first\nx 2024-01-02 second\n  third\n",
+		},
+		{
+			name:    "removed item leaves a blank line",
+			content: "first\nsecond\nthird\n",
+			edit: func(t *testing.T, list *List) {
+				if err := list.Remove(2); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: "first\n\nthird\n",
+		},
+		{
+			name:    "crlf line endings",
+			content: "first\r\nsecond\r\n",
+			edit: func(t *testing.T, list *List) {
+				item, _ := list.Get(1)
+				item.Priority = 'B'
+				if err := list.Set(1, item); err != nil {
+					t.Fatal(err)
+				}
+				list.Add(Item{Message: "third"})
+			},
+			want: "(B) first\r\nsecond\r\nthird\r\n",
+		},
+		{
+			name:    "no final newline",
+			content: "first\nsecond",
+			edit:    func(*testing.T, *List) {},
+			want:    "first\nsecond",
+		},
+		{
+			name:    "crlf with no final newline, item added",
+			content: "a\r\nb",
+			edit: func(_ *testing.T, list *List) {
+				list.Add(Item{Message: "c"})
+			},
+			want: "a\r\nb\r\nc",
+		},
+		{
+			name:    "crlf with no final newline, compacted",
+			content: "a\r\n\r\nb",
+			edit: func(_ *testing.T, list *List) {
+				list.Compact()
+				list.Add(Item{Message: "c"})
+			},
+			want: "a\r\nb\r\nc",
+		},
+		{
+			name:    "compact drops blank lines only",
+			content: "first\n\n# notes\nsecond\n\n",
+			edit: func(_ *testing.T, list *List) {
+				list.Compact()
+				list.Add(Item{Message: "third"})
+			},
+			want: "first\n# notes\nsecond\nthird\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "todo.txt")
+			if err := os.WriteFile(path, []byte(tc.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			list, err := ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			tc.edit(t, list)
+			if err := WriteFile(path, list); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tc.want {
+				t.Errorf("file = %q, want %q", data, tc.want)
+			}
+		})
+	}
+}
+
+func TestWriteFile_Conflict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todo.txt")
+	if err := os.WriteFile(path, []byte("first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	list.Add(Item{Message: "ours"})
+
+	// Another writer changes the file after we read it.
+	if err := os.WriteFile(path, []byte("first\ntheirs\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(path, list); !errors.Is(err, ErrConflict) {
+		t.Fatalf("WriteFile error = %v, want ErrConflict", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "first\ntheirs\n" {
+		t.Errorf("file = %q, want the other writer's content kept", data)
+	}
+
+	// A list that has written the file may write it again.
+	list, err = ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	for _, msg := range []string{"one", "two"} {
+		list.Add(Item{Message: msg})
+		if err := WriteFile(path, list); err != nil {
+			t.Fatalf("WriteFile after adding %q: %v", msg, err)
+		}
+	}
+}
+
+func TestCheckFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todo.txt")
+	if err := os.WriteFile(path, []byte("first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	list, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if err := CheckFile(path, list); err != nil {
+		t.Errorf("CheckFile of an unchanged file = %v, want nil", err)
+	}
+	if err := CheckFile(filepath.Join(t.TempDir(), "other.txt"), list); err != nil {
+		t.Errorf("CheckFile of another file = %v, want nil", err)
+	}
+
+	if err := os.WriteFile(path, []byte("first\ntheirs\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckFile(path, list); !errors.Is(err, ErrConflict) {
+		t.Errorf("CheckFile of a changed file = %v, want ErrConflict", err)
+	}
+}
+
+func TestWriteFile_ConflictOnCreate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todo.txt")
+	list, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	list.Add(Item{Message: "ours"})
+
+	if err := os.WriteFile(path, []byte("theirs\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(path, list); !errors.Is(err, ErrConflict) {
+		t.Errorf("WriteFile error = %v, want ErrConflict", err)
 	}
 }